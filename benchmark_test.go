@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+	"github.com/tuneinsight/lattigo/v6/schemes/ckks"
+	"math"
+	"math/rand"
+	"src/eigen/fixture"
+	"src/eigen/normalize"
+	"sync"
+	"testing"
+)
+
+// The benchmarks run under the fixture preset, on the full 512 slots so that
+// one bootstrapping key set serves every n.
+var benchSizes = []int{4, 8, 11, 16}
+
+type benchContext struct {
+	*fixture.Context
+	evals map[PipelineOptions]*ckks.Evaluator
+}
+
+var (
+	benchOnce sync.Once
+	bench     *benchContext
+)
+
+func getBenchContext() *benchContext {
+	benchOnce.Do(func() {
+		bench = &benchContext{Context: fixture.Get(), evals: map[PipelineOptions]*ckks.Evaluator{}}
+	})
+	return bench
+}
+
+// eval returns the pipeline evaluator for n x n matrices.
+func (ctx *benchContext) eval(n int) *ckks.Evaluator {
+	return ctx.evalWith(PipelineOptions{N: n})
+}
+
+// evalWith returns the pipeline evaluator of opts on the full slots with
+// batch 1, generated once per opts.
+func (ctx *benchContext) evalWith(opts PipelineOptions) *ckks.Evaluator {
+	opts.Slots, opts.Batch = ctx.Params.MaxSlots(), 1
+	if eval, ok := ctx.evals[opts]; ok {
+		return eval
+	}
+	ctx.evals[opts] = ctx.NewEvaluator(PipelineGaloisElements(ctx.Params, opts))
+	return ctx.evals[opts]
+}
+
+// encryptMatrix encrypts the row-major A at the maximum level.
+func (ctx *benchContext) encryptMatrix(A [][]float64) *rlwe.Ciphertext {
+	var rowA []float64
+	for _, row := range A {
+		rowA = append(rowA, row...)
+	}
+	return ctx.Encrypt(rowA, ctx.Params.MaxLevel())
+}
+
+// benchMatrix returns a random symmetric n x n matrix.
+func benchMatrix(n int) (A [][]float64) {
+	r := rand.New(rand.NewSource(int64(n)))
+	A = make([][]float64, n)
+	for i := range A {
+		A[i] = make([]float64, n)
+	}
+	for i := 0; i < n; i++ {
+		for j := i; j < n; j++ {
+			A[i][j] = 2*r.Float64() - 1
+			A[j][i] = A[i][j]
+		}
+	}
+	return A
+}
+
+// benchVector returns a vector of n entries with squared norm 100, within the
+// range of the Newton initial guess.
+func benchVector(n int) (vec []float64) {
+	vec = make([]float64, n)
+	for i := range vec {
+		vec[i] = 10 / math.Sqrt(float64(n))
+	}
+	return vec
+}
+
+func BenchmarkLinearTrans(b *testing.B) {
+	ctx := getBenchContext()
+	for _, n := range benchSizes {
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			eval := ctx.eval(n)
+			A := benchMatrix(n)
+			ctVec := ctx.Encrypt(benchVector(n), ctx.Params.MaxLevel())
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				LinearTrans(A, ctx.Params.MaxSlots(), n, ctVec, ctx.Params, ctx.Ecd, eval)
+			}
+		})
+	}
+}
+
+func BenchmarkHomomoMatMutiVec(b *testing.B) {
+	ctx := getBenchContext()
+	for _, n := range benchSizes {
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			eval := ctx.eval(n)
+			ctVec := ctx.Encrypt(benchVector(n), ctx.Params.MaxLevel())
+			lt, ltEval := LinearTrans(benchMatrix(n), ctx.Params.MaxSlots(), n, ctVec, ctx.Params, ctx.Ecd, eval)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				// A new replicator each time, the cache would skip the replication
+				HomomoMatMutiVec(lt, ltEval, ctVec, eval, NewReplicator(n, MatVecReplicas(ctx.Params.MaxSlots(), n)))
+			}
+		})
+	}
+}
+
+func BenchmarkMulSumVec(b *testing.B) {
+	ctx := getBenchContext()
+	for _, n := range benchSizes {
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			eval := ctx.eval(n)
+			ctVec := ctx.Encrypt(benchVector(n), ctx.Params.MaxLevel())
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				normalize.MulSumVec(eval, ctVec, ctVec, eval, 1, n)
+			}
+		})
+	}
+}
+
+func BenchmarkHomomoNewton(b *testing.B) {
+	ctx := getBenchContext()
+	pta, ptb := ctx.Encode(fixture.A), ctx.Encode(fixture.B)
+	ptf1, ptf2 := ctx.Encode(fixture.F1), ctx.Encode(fixture.F2)
+	for _, n := range benchSizes {
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			eval := ctx.eval(n)
+			ctVec := ctx.Encrypt(benchVector(n), ctx.Params.MaxLevel())
+			ctVecMulSum := normalize.MulSumVec(eval, ctVec, ctVec, eval, 1, n)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				cty0 := normalize.LinearApprox(ctVecMulSum, eval, pta, ptb)
+				normalize.HomomoNewton(ptf1, ptf2, ctVecMulSum, cty0, eval, ctx.BtpEval, fixture.D)
+			}
+		})
+	}
+}
+
+func BenchmarkNormVect(b *testing.B) {
+	ctx := getBenchContext()
+	for _, n := range benchSizes {
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			eval := ctx.eval(n)
+			ctVec := ctx.Encrypt(benchVector(n), ctx.Params.MaxLevel())
+			ctNormVal := ctx.Encrypt([]float64{0.1}, ctx.Params.MaxLevel())
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				normalize.NormVect(ctNormVal, ctVec, eval, eval, n)
+			}
+		})
+	}
+}
+
+func BenchmarkHomomoOuterProduct(b *testing.B) {
+	ctx := getBenchContext()
+	for _, n := range benchSizes {
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			eval := ctx.eval(n)
+			ctVec := ctx.Encrypt(benchVector(n), ctx.Params.MaxLevel())
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				HomomoOuterProduct(ctVec, eval, n, 1, ctx.Params, ctx.Ecd, 1)
+			}
+		})
+	}
+}
+
+func BenchmarkHomomoEigenShift(b *testing.B) {
+	ctx := getBenchContext()
+	for _, n := range benchSizes {
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			eval := ctx.eval(n)
+			ctRowA := ctx.encryptMatrix(benchMatrix(n))
+			ctVec := ctx.Encrypt(benchVector(n), ctx.Params.MaxLevel())
+			ctEigenVal := ctx.Encrypt([]float64{1}, ctx.Params.MaxLevel())
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				HomomoEigenShift(ctRowA, ctVec, ctEigenVal, eval, n, 1, ctx.Params, ctx.Ecd, ctx.BtpEval, 1, nil)
+			}
+		})
+	}
+}
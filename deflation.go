@@ -0,0 +1,53 @@
+package main
+
+import (
+	"github.com/tuneinsight/lattigo/v6/circuits/ckks/bootstrapping"
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+	"github.com/tuneinsight/lattigo/v6/schemes/ckks"
+	"src/eigen/normalize"
+	"src/eigen/tracer"
+)
+
+// Deflation modes, subtracting lambda*v*v^T from the matrix or projecting v out of every iterate.
+const (
+	DeflationHotelling  = "hotelling"
+	DeflationProjection = "projection"
+)
+
+// HomomoProjectOut returns v - sum_j <v, v_j> v_j for the unit eigenvectors v_j of ctDeflVecs.
+func HomomoProjectOut(ctVec *rlwe.Ciphertext, ctDeflVecs []*rlwe.Ciphertext,
+	ptMask *rlwe.Plaintext, eval tracer.Evaluator, ecd *ckks.Encoder, batch int, n int,
+	workers int) (ctProjVec *rlwe.Ciphertext) {
+
+	ctComponents := ParallelEval(workers, len(ctDeflVecs), eval, ecd, func(j int, eval tracer.Evaluator, ecd *ckks.Encoder) *rlwe.Ciphertext {
+		ctDeflVec := ctDeflVecs[j]
+		ctInner := normalize.MulSumVec(eval, ctVec, ctDeflVec, eval, batch, n)
+
+		// Keep only the inner product in slot 0 before broadcasting it
+		ctCoeff, err := eval.MulRelinNew(ctInner, ptMask)
+		if err != nil {
+			panic(err)
+		}
+		if err = eval.Rescale(ctCoeff, ctCoeff); err != nil {
+			panic(err)
+		}
+
+		return normalize.NormVect(ctCoeff, ctDeflVec, eval, eval, n)
+	})
+
+	ctProjVec, err := eval.SubNew(ctVec, SumInOrder(ctComponents, eval))
+	if err != nil {
+		panic(err)
+	}
+
+	return ctProjVec
+}
+
+// HomomoDeflationVec returns a bootstrapped copy of the eigenvector for the projection deflation.
+func HomomoDeflationVec(ctEigenVec *rlwe.Ciphertext, btpEval *bootstrapping.Evaluator) (ctDeflVec *rlwe.Ciphertext) {
+	ctDeflVec, err := btpEval.Evaluate(ctEigenVec.CopyNew())
+	if err != nil {
+		panic(err)
+	}
+	return ctDeflVec
+}
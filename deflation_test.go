@@ -0,0 +1,48 @@
+package main
+
+import (
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+	"math"
+	"src/eigen/fixture"
+	"testing"
+)
+
+func TestHomomoProjectionDeflation(t *testing.T) {
+	if testing.Short() {
+		t.Skip("bootstraps about 60 times")
+	}
+	ctx := getBenchContext()
+
+	// 9, 3, 1, 0.5: the second run, projected against the first eigenvector,
+	// converges to the eigenvalue 3 of the same matrix
+	A := diagonalMatrix(9, 3, 1, 0.5)
+	n := len(A)
+	Slots := ctx.Params.MaxSlots()
+	eval := ctx.eval(n)
+	exactVals, exactVecs := JacobiEigen(A)
+	rep := NewReplicator(n, MatVecReplicas(Slots, n))
+
+	var ctDeflVecs []*rlwe.Ciphertext
+	for i, vec := range [][]float64{{0.6, -0.3, -0.8, 0.4}, {0.2, 0.9, -0.3, 0.2}} {
+		ctVec := ctx.Encrypt(vec, ctx.Params.MaxLevel())
+		lt, ltEval := LinearTrans(A, Slots, n, ctVec, ctx.Params, ctx.Ecd, eval)
+		_, ctEigenVec, ctEigenVal := HomomoPowerMethod(lt, ltEval, ctVec, eval, ctx.Dec, ctx.Ecd, Slots, testIters, 1, n,
+			ctx.Encode(fixture.F1), ctx.Encode(fixture.F2), ctx.Encode(fixture.A), ctx.Encode(fixture.B), ctx.BtpEval,
+			fixture.D, rep, ctDeflVecs, ctx.Encode(1), 1, nil)
+
+		eigenVec, eigenVal := ctx.Decrypt(ctEigenVec, n), ctx.Decrypt(ctEigenVal, 1)[0]
+		if err := math.Abs(eigenVal-exactVals[i]) / exactVals[i]; err > 0.08 {
+			t.Errorf("eigenpair %d: eigenvalue %v, exact %v: relative error %.2e", i+1, eigenVal, exactVals[i], err)
+		}
+		if r := eigenResidual(A, eigenVec, exactVals[i]); r > 0.05 {
+			t.Errorf("eigenpair %d: eigenvector residual %.2e for the exact eigenvalue %v", i+1, r, exactVals[i])
+		}
+		for j := 0; j < i; j++ {
+			if a := angle(eigenVec, exactVecs[j]); math.Abs(a-90) > 2 {
+				t.Errorf("eigenpair %d: eigenvector at %.3f degrees of the eigenvector %d", i+1, a, j+1)
+			}
+		}
+
+		ctDeflVecs = append(ctDeflVecs, HomomoDeflationVec(ctEigenVec, ctx.BtpEval))
+	}
+}
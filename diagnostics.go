@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+	"github.com/tuneinsight/lattigo/v6/schemes/ckks"
+	"math"
+	"os"
+)
+
+// PrecisionRecord is the precision of one stage against its plaintext reference on the decrypted inputs.
+type PrecisionRecord struct {
+	Eigenpair   int      `json:"eigenpair"`
+	Iteration   int      `json:"iteration"`
+	Stage       string   `json:"stage"`
+	Step        int      `json:"step,omitempty"`
+	Level       int      `json:"level"`
+	Log2Scale   float64  `json:"log2_scale"`
+	MinLog2Prec *float64 `json:"min_log2_prec"`
+	AvgLog2Prec *float64 `json:"avg_log2_prec"`
+	MedLog2Prec *float64 `json:"median_log2_prec"`
+	MinLog2Err  *float64 `json:"min_log2_err"`
+	AvgLog2Err  *float64 `json:"avg_log2_err"`
+	MedLog2Err  *float64 `json:"median_log2_err"`
+	MaxLog2Err  *float64 `json:"max_log2_err"`
+}
+
+// PrecisionTrace records the PrecisionRecord of every stage of the power method and the eigen shift.
+type PrecisionTrace struct {
+	params ckks.Parameters
+	ecd    *ckks.Encoder
+	dec    *rlwe.Decryptor
+	Slots  int
+	n      int
+
+	A      [][]float64
+	a, b   float64
+	f1, f2 float64
+	d      int
+
+	Precondition float64
+
+	Eigenpair int
+	Iteration int
+	Records   []PrecisionRecord
+}
+
+func NewPrecisionTrace(params ckks.Parameters, ecd *ckks.Encoder, dec *rlwe.Decryptor, Slots int, n int,
+	d int, a float64, b float64, f1 float64, f2 float64) (trace *PrecisionTrace) {
+
+	return &PrecisionTrace{params: params, ecd: ecd, dec: dec, Slots: Slots, n: n, d: d, a: a, b: b, f1: f1, f2: f2}
+}
+
+// Decrypt returns the first k slots of ct.
+func (trace *PrecisionTrace) Decrypt(ct *rlwe.Ciphertext, k int) (values []float64) {
+	values = make([]float64, k)
+	if err := trace.ecd.Decode(trace.dec.DecryptNew(ct), values); err != nil {
+		panic(err)
+	}
+	return values
+}
+
+// Record appends the precision of the first len(want) slots of ct.
+func (trace *PrecisionTrace) Record(stage string, step int, ct *rlwe.Ciphertext, want []float64) {
+	stats := ckks.GetPrecisionStats(trace.params, trace.ecd, trace.dec, want, ct, 0, false)
+	trace.Records = append(trace.Records, PrecisionRecord{
+		Eigenpair:   trace.Eigenpair,
+		Iteration:   trace.Iteration,
+		Stage:       stage,
+		Step:        step,
+		Level:       ct.Level(),
+		Log2Scale:   ct.Scale.Log2(),
+		MinLog2Prec: finite(stats.MINLog2Prec.Real),
+		AvgLog2Prec: finite(stats.AVGLog2Prec.Real),
+		MedLog2Prec: finite(stats.MEDLog2Prec.Real),
+		MinLog2Err:  finite(stats.MINLog2Err.Real),
+		AvgLog2Err:  finite(stats.AVGLog2Err.Real),
+		MedLog2Err:  finite(stats.MEDLog2Err.Real),
+		MaxLog2Err:  finite(stats.MAXLog2Err.Real),
+	})
+}
+
+// RecordMatVec records the product ctLintransVec of A by ctVec.
+func (trace *PrecisionTrace) RecordMatVec(ctVec *rlwe.Ciphertext, ctLintransVec *rlwe.Ciphertext) {
+	trace.Record("matvec", 0, ctLintransVec, matVec(trace.A, trace.Decrypt(ctVec, trace.n)))
+}
+
+// RecordInnerSum records the inner product ctVecMulSum of ctVec1 and ctVec2.
+func (trace *PrecisionTrace) RecordInnerSum(ctVec1 *rlwe.Ciphertext, ctVec2 *rlwe.Ciphertext,
+	ctVecMulSum *rlwe.Ciphertext) {
+
+	trace.Record("inner sum", 0, ctVecMulSum, []float64{dot(trace.Decrypt(ctVec1, trace.n), trace.Decrypt(ctVec2, trace.n))})
+}
+
+// RecordInitialGuess records the LinearApprox cty0 of ctx0.
+func (trace *PrecisionTrace) RecordInitialGuess(ctx0 *rlwe.Ciphertext, cty0 *rlwe.Ciphertext) {
+	trace.Record("initial guess", 0, cty0, []float64{trace.a*trace.Decrypt(ctx0, 1)[0] + trace.b})
+}
+
+// NewtonSteps returns the step function of HomomoNewtonSteps recording every Newton step.
+func (trace *PrecisionTrace) NewtonSteps(ctx *rlwe.Ciphertext, cty0 *rlwe.Ciphertext) func(i int, cty *rlwe.Ciphertext) {
+	x := trace.Decrypt(ctx, 1)[0]
+	y := trace.Decrypt(cty0, 1)[0]
+	return func(i int, cty *rlwe.Ciphertext) {
+		trace.Record("newton", i+1, cty, []float64{trace.f2*y - trace.f1*x*y*y*y})
+		y = trace.Decrypt(cty, 1)[0]
+	}
+}
+
+// RecordNormalization records the product ctNormVec of ctVec by ctNormVal.
+func (trace *PrecisionTrace) RecordNormalization(ctNormVal *rlwe.Ciphertext, ctVec *rlwe.Ciphertext,
+	ctNormVec *rlwe.Ciphertext) {
+
+	trace.Record("normalization", 0, ctNormVec, scale(trace.Decrypt(ctVec, trace.n), trace.Decrypt(ctNormVal, 1)[0]))
+}
+
+// RecordEigenVal records the HomomoEigenVal ctEigenVal of ctLintransVec and ctNormVec.
+func (trace *PrecisionTrace) RecordEigenVal(ctLintransVec *rlwe.Ciphertext, ctNormVec *rlwe.Ciphertext,
+	ctEigenVal *rlwe.Ciphertext) {
+
+	normVec := trace.Decrypt(ctNormVec, trace.n)
+	normVec2 := dot(normVec, normVec)
+	trace.Record("eigenvalue", 0, ctEigenVal, []float64{dot(trace.Decrypt(ctLintransVec, trace.n), normVec) *
+		ReferenceNewton(normVec2*normVec2, trace.d, trace.a, trace.b, trace.f1, trace.f2)})
+}
+
+// RecordEigenShift records the outer product ctVecOuter and the deflated matrix ctShiftMat.
+func (trace *PrecisionTrace) RecordEigenShift(ctRowVec *rlwe.Ciphertext, ctEigenVec *rlwe.Ciphertext,
+	ctEigenVal *rlwe.Ciphertext, ctVecOuter *rlwe.Ciphertext, ctShiftMat *rlwe.Ciphertext) {
+
+	n := trace.n
+	vec := trace.Decrypt(ctEigenVec, n)
+	outer := make([]float64, n*n)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			outer[i*n+j] = vec[i] * vec[j]
+		}
+	}
+	trace.Record("outer product", 0, ctVecOuter, outer)
+
+	rowVec := trace.Decrypt(ctRowVec, n*n)
+	eigenVal := trace.Decrypt(ctEigenVal, 1)[0]
+	for i := range rowVec {
+		rowVec[i] -= eigenVal * outer[i]
+	}
+	trace.Record("deflation", 0, ctShiftMat, rowVec)
+}
+
+// WriteJSON writes the trace to path.
+func (trace *PrecisionTrace) WriteJSON(path string) {
+	file, err := os.Create(path)
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err = encoder.Encode(struct {
+		LogN         int               `json:"log_n"`
+		Slots        int               `json:"slots"`
+		N            int               `json:"n"`
+		Precondition float64           `json:"precondition"`
+		Records      []PrecisionRecord `json:"records"`
+	}{trace.params.LogN(), trace.Slots, trace.n, trace.Precondition, trace.Records}); err != nil {
+		panic(err)
+	}
+}
+
+func finite(x float64) *float64 {
+	if math.IsInf(x, 0) || math.IsNaN(x) {
+		return nil
+	}
+	return &x
+}
@@ -0,0 +1,124 @@
+// Package fixture is the CKKS context of the -short preset shared by the tests and benchmarks.
+package fixture
+
+import (
+	"github.com/tuneinsight/lattigo/v6/circuits/ckks/bootstrapping"
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+	"github.com/tuneinsight/lattigo/v6/ring"
+	"github.com/tuneinsight/lattigo/v6/schemes/ckks"
+	"github.com/tuneinsight/lattigo/v6/utils"
+	"sync"
+)
+
+// Constants of LinearApprox and HomomoNewton, as in main.
+const (
+	A  = -0.00013651433183402268
+	B  = 0.13651433183402267
+	F1 = 0.5
+	F2 = 1.5
+	D  = 6
+)
+
+type Context struct {
+	Params    ckks.Parameters
+	BtpParams bootstrapping.Parameters
+	Kgen      *rlwe.KeyGenerator
+	Sk        *rlwe.SecretKey
+	Rlk       *rlwe.RelinearizationKey
+	Ecd       *ckks.Encoder
+	Enc       *rlwe.Encryptor
+	Dec       *rlwe.Decryptor
+	BtpEval   *bootstrapping.Evaluator
+}
+
+var (
+	once sync.Once
+	ctx  *Context
+)
+
+// Get returns the context, generated on the first call.
+func Get() *Context {
+	once.Do(func() {
+		params, err := ckks.NewParametersFromLiteral(ckks.ParametersLiteral{
+			LogN: 10,
+			LogQ: []int{55, 40, 40, 40, 40, 40, 40, 40, 40, 40,
+				40, 40, 40, 40, 40, 40, 40},
+			LogP:            []int{61, 61, 61},
+			LogDefaultScale: 40,
+			Xs:              ring.Ternary{H: 192},
+		})
+		if err != nil {
+			panic(err)
+		}
+
+		btpParams, err := bootstrapping.NewParametersFromLiteral(params, bootstrapping.ParametersLiteral{
+			LogN: utils.Pointy(params.LogN()),
+			LogP: []int{61, 61, 61, 61},
+			Xs:   params.Xs(),
+		})
+		if err != nil {
+			panic(err)
+		}
+		btpParams.Mod1ParametersLiteral.LogMessageRatio += 16 - params.LogN()
+
+		kgen := rlwe.NewKeyGenerator(params)
+		sk := kgen.GenSecretKeyNew()
+		btpEvk, _, err := btpParams.GenEvaluationKeys(sk)
+		if err != nil {
+			panic(err)
+		}
+		btpEval, err := bootstrapping.NewEvaluator(btpParams, btpEvk)
+		if err != nil {
+			panic(err)
+		}
+
+		ctx = &Context{
+			Params:    params,
+			BtpParams: btpParams,
+			Kgen:      kgen,
+			Sk:        sk,
+			Rlk:       kgen.GenRelinearizationKeyNew(sk),
+			Ecd:       ckks.NewEncoder(params),
+			Enc:       rlwe.NewEncryptor(params, sk),
+			Dec:       rlwe.NewDecryptor(params, sk),
+			BtpEval:   btpEval,
+		}
+	})
+	return ctx
+}
+
+// NewEvaluator returns an evaluator holding the relinearization key and the keys of galEls.
+func (ctx *Context) NewEvaluator(galEls []uint64) *ckks.Evaluator {
+	return ckks.NewEvaluator(ctx.Params, rlwe.NewMemEvaluationKeySet(ctx.Rlk, ctx.Kgen.GenGaloisKeysNew(galEls, ctx.Sk)...))
+}
+
+// Encrypt encrypts values at level.
+func (ctx *Context) Encrypt(values []float64, level int) *rlwe.Ciphertext {
+	pt := ckks.NewPlaintext(ctx.Params, level)
+	if err := ctx.Ecd.Encode(values, pt); err != nil {
+		panic(err)
+	}
+	ct, err := ctx.Enc.EncryptNew(pt)
+	if err != nil {
+		panic(err)
+	}
+	return ct
+}
+
+// Encode encodes value in slot 0 and zero elsewhere.
+func (ctx *Context) Encode(value float64) *rlwe.Plaintext {
+	pt := ckks.NewPlaintext(ctx.Params, ctx.Params.MaxLevel())
+	if err := ctx.Ecd.Encode([]float64{value}, pt); err != nil {
+		panic(err)
+	}
+	return pt
+}
+
+// Decrypt returns the first k slots of ct.
+func (ctx *Context) Decrypt(ct *rlwe.Ciphertext, k int) (values []float64) {
+	values = make([]float64, k)
+	if err := ctx.Ecd.Decode(ctx.Dec.DecryptNew(ct), values); err != nil {
+		panic(err)
+	}
+	return values
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"github.com/tuneinsight/lattigo/v6/circuits/ckks/bootstrapping"
+	"github.com/tuneinsight/lattigo/v6/circuits/ckks/lintrans"
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+	"src/eigen/normalize"
+	"src/eigen/tracer"
+)
+
+// HomomoSignedPowerMethod iterates with A^2 and returns the signed eigenvalue <Av, v> of the iterate v.
+func HomomoSignedPowerMethod(lt lintrans.LinearTransformation,
+	ltEval *lintrans.Evaluator, ctVec *rlwe.Ciphertext, eval tracer.Evaluator, max_iter int,
+	batch int, n int, ptf1 *rlwe.Plaintext, ptf2 *rlwe.Plaintext,
+	pta *rlwe.Plaintext, ptb *rlwe.Plaintext, btpEval *bootstrapping.Evaluator,
+	d int, rep *Replicator) (ctLintransVec *rlwe.Ciphertext, ctNormVec *rlwe.Ciphertext, ctEigenVal *rlwe.Ciphertext) {
+
+	fmt.Println()
+	fmt.Println("3. Performing homomorphic signed power method...")
+
+	ctNormVec = ctVec
+	for i := 0; i < max_iter; i++ {
+		fmt.Println()
+		fmt.Printf("%2sthe %d-th iteration...", "", i+1)
+		fmt.Println()
+
+		ctNormVec = EnsureLevel(ctNormVec, StageSignedStep, btpEval)
+		ctLintransVec = HomomoMatMutiVec(lt, ltEval, ctNormVec, eval, rep)
+		ctLintransVec = HomomoMatMutiVec(lt, ltEval, ctLintransVec, eval, rep)
+
+		ctVecMulSum := normalize.MulSumVec(eval, ctLintransVec, ctLintransVec, eval, batch, n)
+		cty0 := normalize.LinearApprox(ctVecMulSum, eval, pta, ptb)
+		ctNormVal := normalize.HomomoNewton(ptf1, ptf2, ctVecMulSum, cty0, eval, btpEval, d)
+
+		ctNormVec = normalize.NormVect(ctNormVal, ctLintransVec, eval, eval, n)
+	}
+
+	// A single product keeps the sign of the eigenvalue
+	ctNormVec = EnsureLevel(ctNormVec, StageEigenVal, btpEval)
+	ctLintransVec = HomomoMatMutiVec(lt, ltEval, ctNormVec, eval, rep)
+	ctEigenVal = HomomoEigenVal(ctLintransVec, ctNormVec, eval, batch, n, ptf1, ptf2, pta, ptb, btpEval, d)
+
+	fmt.Println()
+
+	return ctLintransVec, ctNormVec, ctEigenVal
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"src/eigen/fixture"
+	"testing"
+)
+
+func TestHomomoSignedPowerMethod(t *testing.T) {
+	if testing.Short() {
+		t.Skip("bootstraps about 30 times")
+	}
+	ctx := getBenchContext()
+
+	// -3, 2, 1, 0.5: the dominant eigenvalue is negative
+	A := householderConjugate(diagonalMatrix(-3, 2, 1, 0.5), []float64{0.5, 0.5, 0.5, 0.5})
+	n := len(A)
+	Slots := ctx.Params.MaxSlots()
+	eval := ctx.eval(n)
+	vec := []float64{0.6, -0.3, -0.8, 0.4}
+
+	ctVec := ctx.Encrypt(vec, ctx.Params.MaxLevel())
+	lt, ltEval := LinearTrans(A, Slots, n, ctVec, ctx.Params, ctx.Ecd, eval)
+	_, ctEigenVec, ctEigenVal := HomomoSignedPowerMethod(lt, ltEval, ctVec, eval, testIters, 1, n,
+		ctx.Encode(fixture.F1), ctx.Encode(fixture.F2), ctx.Encode(fixture.A), ctx.Encode(fixture.B), ctx.BtpEval, fixture.D,
+		NewReplicator(n, MatVecReplicas(Slots, n)))
+
+	refVec, refVal := ReferencePowerMethod(A, vec, testIters, true, fixture.D, fixture.A, fixture.B, fixture.F1, fixture.F2)
+	exactVals, _ := JacobiEigen(A)
+	checkEigenpair(t, A, ctx.Decrypt(ctEigenVec, n), ctx.Decrypt(ctEigenVal, 1)[0], refVec, refVal, exactVals[0])
+}
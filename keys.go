@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"github.com/tuneinsight/lattigo/v6/circuits/ckks/lintrans"
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+	"github.com/tuneinsight/lattigo/v6/ring"
+	"github.com/tuneinsight/lattigo/v6/schemes/ckks"
+	"slices"
+	"src/eigen/tracer"
+)
+
+// PipelineOptions are the size and the modes of the SVD pipeline.
+type PipelineOptions struct {
+	// Slots is the number of slots of the ciphertexts.
+	Slots int
+	// N is the size of the n x n matrix, or the number of columns of the PCA data.
+	N int
+	// Batch is the batch of the inner sums and replications, 1 in main.
+	Batch int
+	// Starts is the number of start vectors compared by HomomoSelectBest.
+	Starts int
+	// Matrices is the number of matrices of a batch SVD.
+	Matrices int
+	// Square adds the matrix square of the nearest spectrum mode.
+	Square bool
+	// PCARows is the number of rows of the PCA data, 0 without PCA.
+	PCARows int
+	// Project is the number of components new samples are projected onto.
+	Project int
+	// Reconstruct adds the squared residual norm of the low-rank reconstruction.
+	Reconstruct bool
+	// Variance adds the trace and comparison of the explained-variance stopping.
+	Variance bool
+}
+
+// PipelineGaloisElements returns every Galois element used by the SVD pipeline of opts.
+func PipelineGaloisElements(params ckks.Parameters, opts PipelineOptions) (galEls []uint64) {
+	Slots, n, batch := opts.Slots, opts.N, opts.Batch
+
+	galEls = append(galEls, params.GaloisElementsForInnerSum(batch, n)...)
+	galEls = append(galEls, params.GaloisElementsForReplicate(batch, n)...)
+	galEls = append(galEls, params.GaloisElementsForReplicate(batch, n*n)...)
+	galEls = append(galEls, ReplicatorGaloisElements(params, n, MatVecReplicas(Slots, n))...)
+	galEls = append(galEls, LinearTransGaloisElements(params, Slots, n)...)
+	galEls = append(galEls, OuterProductGaloisElements(params, n, batch)...)
+	if opts.Square {
+		galEls = append(galEls, MatSquareGaloisElements(params, n)...)
+	}
+	if opts.Starts > 1 || opts.Matrices > 1 {
+		galEls = append(galEls, ReplicatorGaloisElements(params, n, 2)...)
+	}
+	if opts.Starts > 1 {
+		galEls = append(galEls, SelectBestGaloisElements(params, opts.Starts, MultiStartStride(n))...)
+	}
+	if opts.PCARows > 0 {
+		galEls = append(galEls, PCAGaloisElements(params, opts.PCARows, n)...)
+	}
+	if opts.Project > 0 {
+		galEls = append(galEls, ProjectGaloisElements(params, Slots, n, opts.Project)...)
+	}
+	if opts.Reconstruct {
+		galEls = append(galEls, ReconstructionGaloisElements(params, n)...)
+	}
+	if opts.Variance {
+		galEls = append(galEls, VarianceGaloisElements(params, n)...)
+	}
+
+	slices.Sort(galEls)
+	return slices.Compact(galEls)
+}
+
+// linearTransParams returns the parameters of the product by the n diagonals of an n x n matrix.
+func linearTransParams(params ckks.Parameters, n int, level int, logDimensions ring.Dimensions) lintrans.Parameters {
+	diagonalsIndexList := make([]int, n)
+	for k := 0; k < n; k++ {
+		diagonalsIndexList[k] = k
+	}
+
+	return lintrans.Parameters{
+		DiagonalsIndexList:        diagonalsIndexList,
+		LevelQ:                    level,
+		LevelP:                    params.MaxLevelP(),
+		Scale:                     rlwe.NewScale(params.Q()[level]),
+		LogDimensions:             logDimensions,
+		LogBabyStepGiantStepRatio: 1,
+	}
+}
+
+func LinearTransGaloisElements(params ckks.Parameters, Slots int, n int) (galEls []uint64) {
+	logDimensions := NewSlotsPlaintext(params, params.MaxLevel(), Slots).LogDimensions
+	return lintrans.GaloisElements(params, linearTransParams(params, n, params.MaxLevel(), logDimensions))
+}
+
+func OuterProductGaloisElements(params ckks.Parameters, n int, batch int) (galEls []uint64) {
+	galEls = append(galEls, params.GaloisElementsForReplicate(n*batch, n)...)
+	galEls = append(galEls, params.GaloisElementsForReplicate(batch, n)...)
+	return append(galEls, params.GaloisElementsForInnerSum(batch, n)...)
+}
+
+func MatSquareGaloisElements(params ckks.Parameters, n int) (galEls []uint64) {
+	for k := 1; k < n; k++ {
+		galEls = append(galEls, params.GaloisElement(k*n))
+	}
+	return galEls
+}
+
+func SelectBestGaloisElements(params ckks.Parameters, starts int, stride int) (galEls []uint64) {
+	for k := 1; k < starts; k <<= 1 {
+		galEls = append(galEls, params.GaloisElement(k*stride))
+	}
+	return append(galEls, params.GaloisElementForComplexConjugation())
+}
+
+// NewPipelineEvaluator returns an evaluator holding the keys of galEls and rlk.
+func NewPipelineEvaluator(params ckks.Parameters, galEls []uint64, kgen *rlwe.KeyGenerator,
+	rlk *rlwe.RelinearizationKey, sk *rlwe.SecretKey) (eval *ckks.Evaluator) {
+
+	return ckks.NewEvaluator(params, rlwe.NewMemEvaluationKeySet(rlk, kgen.GenGaloisKeysNew(galEls, sk)...))
+}
+
+// CheckGaloisKeys returns an error for the first element of galEls without a key in eval.
+func CheckGaloisKeys(eval tracer.Evaluator, galEls []uint64) (err error) {
+	for _, galEl := range galEls {
+		if _, err = eval.CheckAndGetGaloisKey(galEl); err != nil {
+			return fmt.Errorf("missing Galois key for element %d: %w", galEl, err)
+		}
+	}
+	return nil
+}
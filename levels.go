@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"github.com/tuneinsight/lattigo/v6/circuits/ckks/bootstrapping"
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+)
+
+// newtonMinLevel is the level HomomoNewton needs on the output of MulSumVec.
+const newtonMinLevel = 6
+
+// LevelStage is a power method stage needing its ciphertext at MinLevel and leaving it Cost levels lower.
+type LevelStage struct {
+	Name     string
+	MinLevel int
+	Cost     int
+	Newton   int
+}
+
+// Levels consumed by the operations of the stages, each rescaled once.
+const (
+	levelsMatVec    = 1 // HomomoMatMutiVec
+	levelsMulSumVec = 1 // normalize.MulSumVec
+	levelsNormVect  = 1 // normalize.NormVect
+	levelsMul       = 1 // a product by a mask or a ciphertext
+)
+
+var (
+	// MulSumVec, a mask and NormVect
+	StageProjection = LevelStage{Name: "projection", MinLevel: levelsMulSumVec + levelsMul + levelsNormVect,
+		Cost: levelsMulSumVec + levelsMul + levelsNormVect}
+	// HomomoMatMutiVec and MulSumVec before HomomoNewton, then NormVect
+	StagePowerStep = LevelStage{Name: "product and normalization", MinLevel: newtonMinLevel + levelsMatVec +
+		levelsMulSumVec, Cost: levelsMatVec + levelsNormVect, Newton: 1}
+	// MulSumVec and a square before HomomoNewton, the iterate is only read
+	StageEigenVal = LevelStage{Name: "eigenvalue", MinLevel: newtonMinLevel + levelsMulSumVec + levelsMul, Cost: 0,
+		Newton: 1}
+	// The two masks and the product of the outer product, then the product by lambda
+	StageEigenShift = LevelStage{Name: "eigen shift", MinLevel: 4 * levelsMul, Cost: 4 * levelsMul}
+	// The product of the replicated lambda by the outer product
+	StageEigenShiftVal = LevelStage{Name: "eigen shift value", MinLevel: levelsMul, Cost: levelsMul}
+	// HomomoMatMutiVec, the quotient by MulSumVec and the shift mask, NormVect
+	// of the shifted iterate, then MulSumVec before HomomoNewton and NormVect
+	StageRayleighStep = LevelStage{Name: "shifted product", MinLevel: newtonMinLevel + levelsMatVec + levelsMulSumVec +
+		levelsMul + levelsNormVect + levelsMulSumVec, Cost: levelsMatVec + levelsMulSumVec + levelsMul +
+		2*levelsNormVect, Newton: 1}
+	// HomomoMatMutiVec, MulSumVec and the mask of the quotient, the iterate is only read
+	StageRayleighQuotient = LevelStage{Name: "Rayleigh quotient", MinLevel: levelsMatVec + levelsMulSumVec + levelsMul,
+		Cost: 0}
+	// Two HomomoMatMutiVec and MulSumVec before HomomoNewton, then NormVect
+	StageSignedStep = LevelStage{Name: "squared product", MinLevel: newtonMinLevel + 2*levelsMatVec + levelsMulSumVec,
+		Cost: 2*levelsMatVec + levelsNormVect, Newton: 1}
+)
+
+// PowerMethodStages returns the stages of one eigenpair of HomomoPowerMethod.
+func PowerMethodStages(max_iter int, project bool, shift bool) (stages []LevelStage) {
+	for i := 0; i < max_iter; i++ {
+		if project {
+			stages = append(stages, StageProjection)
+		}
+		stages = append(stages, StagePowerStep)
+	}
+	stages = append(stages, StageEigenVal)
+	if shift {
+		stages = append(stages, StageEigenShift)
+	}
+	return stages
+}
+
+// RayleighStages returns the stages of one eigenpair of HomomoRayleighPowerMethod.
+func RayleighStages(max_iter int, shifted bool, shift bool) (stages []LevelStage) {
+	for i := 0; i < max_iter; i++ {
+		if shifted && i > 0 {
+			stages = append(stages, StageRayleighStep)
+		} else {
+			stages = append(stages, StagePowerStep)
+		}
+	}
+	stages = append(stages, StageRayleighQuotient)
+	if shift {
+		stages = append(stages, StageEigenShift)
+	}
+	return stages
+}
+
+// SignedStages returns the stages of one eigenpair of HomomoSignedPowerMethod.
+func SignedStages(max_iter int, shift bool) (stages []LevelStage) {
+	for i := 0; i < max_iter; i++ {
+		stages = append(stages, StageSignedStep)
+	}
+	stages = append(stages, StageEigenVal)
+	if shift {
+		stages = append(stages, StageEigenShift)
+	}
+	return stages
+}
+
+// PlannedStage is a LevelStage with its input and output levels.
+type PlannedStage struct {
+	LevelStage
+	In        int
+	Out       int
+	Bootstrap bool
+}
+
+// LevelPlan is the levels of a ciphertext through the stages.
+type LevelPlan struct {
+	Level    int
+	BtpLevel int
+	Stages   []PlannedStage
+}
+
+// PlanLevels returns the plan of stages from level, bootstrapping as EnsureLevel does.
+func PlanLevels(level int, btpLevel int, stages []LevelStage) (plan LevelPlan) {
+	plan = LevelPlan{Level: level, BtpLevel: btpLevel}
+	for _, stage := range stages {
+		if stage.MinLevel > btpLevel {
+			panic(fmt.Errorf("stage %s needs level %d above the bootstrapping output level %d",
+				stage.Name, stage.MinLevel, btpLevel))
+		}
+		planned := PlannedStage{LevelStage: stage, Bootstrap: level < stage.MinLevel}
+		if planned.Bootstrap {
+			level = btpLevel
+		}
+		planned.In = level
+		level -= stage.Cost
+		planned.Out = level
+		plan.Stages = append(plan.Stages, planned)
+	}
+	return plan
+}
+
+// Bootstraps returns the number of bootstrappings of the plan, Newton ones included.
+func (plan LevelPlan) Bootstraps(d int) (count int) {
+	for _, stage := range plan.Stages {
+		if stage.Bootstrap {
+			count++
+		}
+		count += stage.Newton * d
+	}
+	return count
+}
+
+// Print writes the per-stage level report of the plan.
+func (plan LevelPlan) Print(d int) {
+	fmt.Printf("%4sstart level %d, bootstrapping output level %d\n", "", plan.Level, plan.BtpLevel)
+	fmt.Printf("%4s%-3s %-26s %4s %4s %4s %9s %7s\n", "", "#", "stage", "min", "in", "out", "bootstrap", "newton")
+	for i, stage := range plan.Stages {
+		btp := ""
+		if stage.Bootstrap {
+			btp = "before"
+		}
+		fmt.Printf("%4s%-3d %-26s %4d %4d %4d %9s %7d\n", "", i+1, stage.Name, stage.MinLevel, stage.In, stage.Out,
+			btp, stage.Newton*d)
+	}
+	fmt.Printf("%4stotal bootstrappings: %d\n", "", plan.Bootstraps(d))
+}
+
+// EnsureLevel returns ct, bootstrapped if it is below the minimum level of stage.
+func EnsureLevel(ct *rlwe.Ciphertext, stage LevelStage, btpEval *bootstrapping.Evaluator) *rlwe.Ciphertext {
+	if ct.Level() >= stage.MinLevel {
+		return ct
+	}
+
+	ctOut, err := btpEval.Evaluate(ct.CopyNew())
+	if err != nil {
+		panic(err)
+	}
+	return ctOut
+}
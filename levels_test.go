@@ -0,0 +1,248 @@
+package main
+
+import (
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+	"math"
+	"slices"
+	"src/eigen/fixture"
+	"src/eigen/normalize"
+	"testing"
+)
+
+func TestPlanLevels(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		stages     []LevelStage
+		in, out    []int
+		bootstraps int
+	}{
+		{
+			// Four steps of two levels, then the eigenvalue and the shift without bootstrapping
+			name:       "power method",
+			stages:     PowerMethodStages(4, false, true),
+			in:         []int{16, 14, 12, 10, 8, 8},
+			out:        []int{14, 12, 10, 8, 8, 4},
+			bootstraps: 5 * fixture.D,
+		},
+		{
+			name:       "projection deflation",
+			stages:     PowerMethodStages(2, true, false),
+			in:         []int{16, 13, 11, 8, 12},
+			out:        []int{13, 11, 8, 6, 12},
+			bootstraps: 1 + 3*fixture.D,
+		},
+		{
+			// Every shifted step after the second one starts from the bootstrapping
+			name:       "shifted Rayleigh",
+			stages:     RayleighStages(4, true, false),
+			in:         []int{16, 14, 12, 12, 7},
+			out:        []int{14, 9, 7, 7, 7},
+			bootstraps: 2 + 4*fixture.D,
+		},
+		{
+			name:       "unshifted Rayleigh",
+			stages:     RayleighStages(4, false, false),
+			in:         []int{16, 14, 12, 10, 8},
+			out:        []int{14, 12, 10, 8, 8},
+			bootstraps: 4 * fixture.D,
+		},
+		{
+			name:       "signed",
+			stages:     SignedStages(4, true),
+			in:         []int{16, 13, 10, 12, 9, 9},
+			out:        []int{13, 10, 7, 9, 9, 5},
+			bootstraps: 1 + 5*fixture.D,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			plan := PlanLevels(16, 12, tc.stages)
+			var in, out []int
+			for _, stage := range plan.Stages {
+				in, out = append(in, stage.In), append(out, stage.Out)
+				if stage.In < stage.MinLevel {
+					t.Errorf("stage %s starts at level %d below its minimum %d", stage.Name, stage.In, stage.MinLevel)
+				}
+			}
+			if !slices.Equal(in, tc.in) || !slices.Equal(out, tc.out) {
+				t.Errorf("levels in %v out %v, want in %v out %v", in, out, tc.in, tc.out)
+			}
+			if have := plan.Bootstraps(fixture.D); have != tc.bootstraps {
+				t.Errorf("%d bootstrappings, want %d", have, tc.bootstraps)
+			}
+		})
+	}
+}
+
+func TestPlanLevelsBelowBootstrapping(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("no panic for a stage above the bootstrapping output level")
+		}
+	}()
+	PlanLevels(16, StageRayleighStep.MinLevel-1, RayleighStages(2, true, false))
+}
+
+func TestEnsureLevel(t *testing.T) {
+	if testing.Short() {
+		t.Skip("bootstraps once")
+	}
+	ctx := getBenchContext()
+	vec := []float64{1, 2, 3, 4}
+
+	ct := ctx.Encrypt(vec, StagePowerStep.MinLevel)
+	if have := EnsureLevel(ct, StagePowerStep, ctx.BtpEval); have != ct {
+		t.Errorf("ciphertext at the minimum level %d bootstrapped", ct.Level())
+	}
+
+	ct = ctx.Encrypt(vec, StagePowerStep.MinLevel-1)
+	have := EnsureLevel(ct, StagePowerStep, ctx.BtpEval)
+	if have.Level() != ctx.BtpEval.OutputLevel() {
+		t.Errorf("level %d, want the bootstrapping output level %d", have.Level(), ctx.BtpEval.OutputLevel())
+	}
+	for i, val := range ctx.Decrypt(have, len(vec)) {
+		if diff := val - vec[i]; diff > 1e-4 || diff < -1e-4 {
+			t.Fatalf("slot %d: have %v, want %v", i, val, vec[i])
+		}
+	}
+}
+
+// plannedStages returns the stages of plan that are stage, in order.
+func plannedStages(plan LevelPlan, stage LevelStage) (stages []PlannedStage) {
+	for _, planned := range plan.Stages {
+		if planned.LevelStage == stage {
+			stages = append(stages, planned)
+		}
+	}
+	return stages
+}
+
+// TestStageLevels runs the stage sequences with a single Newton step, which
+// leaves the levels as they are, and checks the levels of the iterate the
+// methods go through against their plan: from the precision trace of
+// HomomoPowerMethod, the emitted iterates of HomomoRayleighPowerMethod and the
+// iterates returned by HomomoSignedPowerMethod after every number of steps.
+func TestStageLevels(t *testing.T) {
+	if testing.Short() {
+		t.Skip("bootstraps about 40 times")
+	}
+	ctx := getBenchContext()
+	A := householderConjugate(diagonalMatrix(9, 6, 5, 4), []float64{0.5, 0.5, 0.5, 0.5})
+	vec := []float64{0.6, -0.3, -0.8, 0.4}
+	n, Slots, maxLevel, btpLevel := len(A), ctx.Params.MaxSlots(), ctx.Params.MaxLevel(), ctx.BtpEval.OutputLevel()
+	eval := ctx.eval(n)
+	ptf1, ptf2, pta, ptb := ctx.Encode(fixture.F1), ctx.Encode(fixture.F2), ctx.Encode(fixture.A), ctx.Encode(fixture.B)
+
+	start := func() (*rlwe.Ciphertext, *Replicator) {
+		return ctx.Encrypt(vec, maxLevel), NewReplicator(n, MatVecReplicas(Slots, n))
+	}
+	checkLevel := func(t *testing.T, what string, have int, want int) {
+		t.Helper()
+		if have != want {
+			t.Errorf("%s at level %d, planned %d", what, have, want)
+		}
+	}
+
+	for _, project := range []bool{false, true} {
+		name, iters := "power method", 6
+		if project {
+			name, iters = "projection deflation", 3
+		}
+		t.Run(name, func(t *testing.T) {
+			var ctDeflVecs []*rlwe.Ciphertext
+			if project {
+				ctDeflVecs = append(ctDeflVecs, HomomoDeflationVec(ctx.Encrypt([]float64{0, 1}, maxLevel), ctx.BtpEval))
+			}
+			trace := NewPrecisionTrace(ctx.Params, ctx.Ecd, ctx.Dec, Slots, n, 1, fixture.A, fixture.B, fixture.F1,
+				fixture.F2)
+			trace.A = A
+			ctVec, rep := start()
+			lt, ltEval := LinearTrans(A, Slots, n, ctVec, ctx.Params, ctx.Ecd, eval)
+			_, ctEigenVec, ctEigenVal := HomomoPowerMethod(lt, ltEval, ctVec, eval, ctx.Dec, ctx.Ecd, Slots, iters, 1,
+				n, ptf1, ptf2, pta, ptb, ctx.BtpEval, 1, rep, ctDeflVecs, ctx.Encode(1), 1, trace)
+
+			plan := PlanLevels(maxLevel, btpLevel, PowerMethodStages(iters, project, true))
+			steps := plannedStages(plan, StagePowerStep)
+			var matvecs, norms []int
+			for _, rec := range trace.Records {
+				switch rec.Stage {
+				case "matvec":
+					matvecs = append(matvecs, rec.Level)
+				case "normalization":
+					norms = append(norms, rec.Level)
+				}
+			}
+			if len(matvecs) != iters || len(norms) != iters {
+				t.Fatalf("%d products and %d normalizations traced, want %d", len(matvecs), len(norms), iters)
+			}
+			for i, step := range steps {
+				checkLevel(t, "product of the iterate", matvecs[i], step.In-levelsMatVec)
+				checkLevel(t, "normalized iterate", norms[i], step.Out)
+			}
+
+			eigenVal := plannedStages(plan, StageEigenVal)[0]
+			checkLevel(t, "eigenvector", ctEigenVec.Level(), eigenVal.In)
+			_, ctRankOne := HomomoRankOne(ctEigenVec, ctEigenVal, eval, n, 1, ctx.Params, ctx.Ecd, ctx.BtpEval, 1)
+			checkLevel(t, "rank-one term", ctRankOne.Level(), plannedStages(plan, StageEigenShift)[0].Out)
+		})
+	}
+
+	t.Run("shifted Rayleigh", func(t *testing.T) {
+		const iters = 3
+		var levels []int
+		emit := func(i int, ctNormVec *rlwe.Ciphertext, _ *rlwe.Ciphertext) {
+			levels = append(levels, ctNormVec.Level())
+		}
+		ctVec, rep := start()
+		lt, ltEval := LinearTrans(A, Slots, n, ctVec, ctx.Params, ctx.Ecd, eval)
+		HomomoRayleighPowerMethod(lt, ltEval, ctVec, eval, iters, 1, n, ptf1, ptf2, pta, ptb, ctx.Encode(1),
+			ctx.Encode(0.45), ctx.BtpEval, 1, rep, emit)
+
+		// Every iterate but the start vector is emitted as it enters its stage
+		plan := PlanLevels(maxLevel, btpLevel, RayleighStages(iters, true, false))
+		for i, level := range levels {
+			checkLevel(t, "iterate", level, plan.Stages[i+1].In)
+		}
+	})
+
+	t.Run("signed", func(t *testing.T) {
+		for iters := 1; iters <= 4; iters++ {
+			ctVec, rep := start()
+			lt, ltEval := LinearTrans(A, Slots, n, ctVec, ctx.Params, ctx.Ecd, eval)
+			_, ctEigenVec, _ := HomomoSignedPowerMethod(lt, ltEval, ctVec, eval, iters, 1, n, ptf1, ptf2, pta, ptb,
+				ctx.BtpEval, 1, rep)
+
+			plan := PlanLevels(maxLevel, btpLevel, SignedStages(iters, false))
+			checkLevel(t, "eigenvector", ctEigenVec.Level(), plan.Stages[iters].In)
+		}
+	})
+}
+
+// TestNewtonMinLevel runs a Newton step from inputs at newtonMinLevel, where
+// it holds, and one level below, where the bootstrapping refuses its scale.
+func TestNewtonMinLevel(t *testing.T) {
+	if testing.Short() {
+		t.Skip("bootstraps once")
+	}
+	ctx := getBenchContext()
+	eval := ctx.eval(1)
+	x := 16.0
+	newtonStep := func(level int) float64 {
+		ctx0 := ctx.Encrypt([]float64{x}, level)
+		cty0 := normalize.LinearApprox(ctx0, eval, ctx.Encode(fixture.A), ctx.Encode(fixture.B))
+		return ctx.Decrypt(normalize.HomomoNewton(ctx.Encode(fixture.F1), ctx.Encode(fixture.F2), ctx0, cty0, eval,
+			ctx.BtpEval, 1), 1)[0]
+	}
+
+	y0 := fixture.A*x + fixture.B
+	want := y0 * (fixture.F2 - fixture.F1*x*y0*y0)
+	if have := newtonStep(newtonMinLevel); math.Abs(have-want)/want > 1e-3 {
+		t.Errorf("Newton step at level %d: have %v, want %v", newtonMinLevel, have, want)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("no panic for a Newton step at level %d", newtonMinLevel-1)
+		}
+	}()
+	newtonStep(newtonMinLevel - 1)
+}
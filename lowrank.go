@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/csv"
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+	"github.com/tuneinsight/lattigo/v6/schemes/ckks"
+	"os"
+	"src/eigen/normalize"
+	"src/eigen/tracer"
+	"strconv"
+)
+
+// ReconstructionGaloisElements returns the rotations of HomomoResidualNorm2.
+func ReconstructionGaloisElements(params ckks.Parameters, n int) (galEls []uint64) {
+	return params.GaloisElementsForInnerSum(1, n*n)
+}
+
+// HomomoAccumulate returns ctRowApprox + ctRankOne, or ctRankOne when ctRowApprox is nil.
+func HomomoAccumulate(ctRowApprox *rlwe.Ciphertext, ctRankOne *rlwe.Ciphertext,
+	eval tracer.Evaluator) (ctSum *rlwe.Ciphertext) {
+
+	if ctRowApprox == nil {
+		return ctRankOne
+	}
+	ctSum, err := eval.AddNew(ctRowApprox, ctRankOne)
+	if err != nil {
+		panic(err)
+	}
+	return ctSum
+}
+
+// HomomoResidualNorm2 returns the squared residual ||A - A_k||_F^2 in slot 0.
+func HomomoResidualNorm2(ctRowA *rlwe.Ciphertext, ctRowApprox *rlwe.Ciphertext, eval tracer.Evaluator,
+	n int, params ckks.Parameters) (ctNorm2 *rlwe.Ciphertext) {
+
+	if err := CheckGaloisKeys(eval, ReconstructionGaloisElements(params, n)); err != nil {
+		panic(err)
+	}
+
+	ctResidual, err := eval.SubNew(ctRowA, ctRowApprox)
+	if err != nil {
+		panic(err)
+	}
+	return normalize.MulSumVec(eval, ctResidual, ctResidual, eval, 1, n*n)
+}
+
+// WriteReconstruction writes the rows of the decrypted approximation A_k.
+func WriteReconstruction(path string, approx [][]float64) {
+	file, err := os.Create(path)
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	for _, values := range approx {
+		var row []string
+		for _, val := range values {
+			row = append(row, strconv.FormatFloat(val, 'f', 20, 64))
+		}
+		if err = writer.Write(row); err != nil {
+			panic(err)
+		}
+	}
+}
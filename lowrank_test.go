@@ -0,0 +1,39 @@
+package main
+
+import (
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+	"math"
+	"testing"
+)
+
+func TestHomomoResidualNorm2(t *testing.T) {
+	ctx := getBenchContext()
+
+	// 12, 4, 4, 0: the rank-1 and rank-2 residuals are 32 and 16
+	A := spectrumCases[1].A
+	n := len(A)
+	eval := ctx.evalWith(PipelineOptions{N: n, Reconstruct: true})
+	eigenVals, eigenVecs := JacobiEigen(A)
+	ctRowA := ctx.encryptMatrix(A)
+
+	var ctRowApprox *rlwe.Ciphertext
+	for k := 1; k <= 2; k++ {
+		rankOne := subOuter(diagonalMatrix(make([]float64, n)...), -eigenVals[k-1], eigenVecs[k-1])
+		ctRowApprox = HomomoAccumulate(ctRowApprox, ctx.encryptMatrix(rankOne), eval)
+
+		R := reconstructionResidual(A, eigenVecs[:k], eigenVals[:k])
+		approx := ctx.Decrypt(ctRowApprox, n*n)
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				if want := A[i][j] - R[i][j]; math.Abs(approx[i*n+j]-want) > 1e-4 {
+					t.Fatalf("rank %d: entry (%d, %d) of A_k is %v, want %v", k, i, j, approx[i*n+j], want)
+				}
+			}
+		}
+
+		have := ctx.Decrypt(HomomoResidualNorm2(ctRowA, ctRowApprox, eval, n, ctx.Params), 1)[0]
+		if want := frobenius2(R); math.Abs(have-want) > 1e-3 {
+			t.Errorf("rank %d: ||A - A_k||_F^2 = %v, want %v", k, have, want)
+		}
+	}
+}
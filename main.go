@@ -5,23 +5,53 @@ import (
 	"flag"
 	"fmt"
 	"github.com/tuneinsight/lattigo/v6/circuits/ckks/bootstrapping"
+	"github.com/tuneinsight/lattigo/v6/circuits/ckks/comparison"
+	"github.com/tuneinsight/lattigo/v6/circuits/ckks/lintrans"
 	"github.com/tuneinsight/lattigo/v6/core/rlwe"
 	"github.com/tuneinsight/lattigo/v6/ring"
 	"github.com/tuneinsight/lattigo/v6/schemes/ckks"
 	"github.com/tuneinsight/lattigo/v6/utils"
 	"math"
-	"math/rand"
 	"os"
+	"src/eigen/tracer"
 	"strconv"
+	"strings"
 	"time"
 )
 
 var flagShort = flag.Bool("short", false, "run the example with a smaller and insecure ring degree.")
+var flagStarts = flag.Int("starts", 1, "number of random start vectors iterated in parallel for each eigenpair.")
+var flagRayleigh = flag.Bool("rayleigh", false, "estimate the eigenvalue with the Rayleigh quotient of the normalized iterate.")
+var flagRayleighShift = flag.Float64("rayleigh-shift", 0, "with -rayleigh, iterate with A - shift*theta*I where theta is the current encrypted quotient, shift in [0, 0.5) for a positive semi-definite A.")
+var flagRayleighEmit = flag.Bool("rayleigh-emit", false, "with -rayleigh, decrypt and print the quotient at every iteration.")
+var flagIndefinite = flag.Bool("indefinite", false, "iterate with A^2 to support symmetric matrices with negative eigenvalues.")
+var flagDeflation = flag.String("deflation", DeflationHotelling, "deflation between eigenpairs: hotelling or projection.")
+var flagSparse = flag.Bool("sparse", true, "encode and bootstrap only the slots of the padded problem size instead of all the slots.")
+var flagMatrices = flag.String("matrices", "", "comma-separated CSV files of same-size matrices decomposed at once in disjoint slot blocks.")
+var flagWorkers = flag.Int("workers", 1, "number of goroutines running independent ciphertext operations.")
+var flagSpectrum = flag.String("spectrum", SpectrumLargest, "eigenpairs to compute: largest, smallest or nearest (to -target).")
+var flagBound = flag.Float64("bound", 0, "with -spectrum smallest or nearest, the shift constant c (0 uses a Gershgorin bound).")
+var flagTarget = flag.Float64("target", 0, "with -spectrum nearest, the value the eigenvalues are searched around.")
+var flagPrecision = flag.String("precision", "", "write the per-stage CKKS precision against the plaintext reference as a JSON trace to this file.")
+var flagTraceOps = flag.String("trace-ops", "", "write the level, scale and estimated noise of every evaluator operation as JSON to this file, and print the flagged ones.")
+var flagPrecondition = flag.String("precondition", PreconditionNone, "scale A before encryption by a public factor undone on the eigenvalues: none, trace or frobenius.")
+var flagPCA = flag.String("pca", "", "CSV file of an m x p data matrix whose covariance is built homomorphically and decomposed instead of A (PCA).")
+var flagProject = flag.String("project", "", "CSV file of new samples projected homomorphically onto the computed components, the scores written to result/scores.csv.")
+var flagProjectPlain = flag.Bool("project-plain", false, "with -project, project onto the decrypted components held by the model owner instead of the encrypted ones.")
+var flagReconstruct = flag.Bool("reconstruct", false, "accumulate the encrypted rank-k approximation A_k of the computed eigenpairs and its squared residual norm ||A - A_k||_F^2.")
+var flagVariance = flag.Float64("variance", 0, "if > 0, compute eigenpairs until they explain this fraction of tr(A) instead of a fixed number, from an encrypted trace and running sum.")
+var flagVarianceReveal = flag.String("variance-reveal", VarianceRevealBit, "with -variance, the only value decrypted after every eigenpair: bit (whether the fraction of tr(A) is reached) or ratio (the fraction explained so far). The eigenpairs are decrypted after the last one.")
+var flagSeed = flag.Int64("seed", 0, "if not 0, seed every key, the encryption noise and the start vectors so that runs are identical (INSECURE).")
+var flagReport = flag.Bool("report", true, "compare the decrypted eigenpairs with a plaintext power method and the exact eigendecomposition.")
 
 func main() {
 
 	flag.Parse()
 
+	if *flagSeed != 0 {
+		fmt.Printf("WARNING: seeded randomness (-seed %d), the keys and ciphertexts are insecure\n", *flagSeed)
+	}
+
 	LogN := 13
 
 	if *flagShort {
@@ -47,7 +77,116 @@ func main() {
 	}
 
 	//prec := params.EncodingPrecision()
-	Slots := params.MaxSlots()
+
+	//file, err := os.Open("data/Yale_left.csv")
+	//file, err := os.Open("data/Yale_right.csv")
+	//file, err := os.Open("data/Air_left.csv")
+	//file, err := os.Open("data/Air_right.csv")
+	//file, err := os.Open("data/wine_left.csv")
+	file, err := os.Open("data/wine_right.csv")
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	records, err := reader.ReadAll()
+	if err != nil {
+		panic(err)
+	}
+	//
+	var A [][]float64
+	for _, row := range records {
+		var floatRow []float64
+		for _, val := range row {
+			f, err := strconv.ParseFloat(val, 64)
+			if err != nil {
+				panic(err)
+			}
+			floatRow = append(floatRow, f)
+		}
+		A = append(A, floatRow)
+	}
+
+	// Batch of matrices sharing the same ciphertexts, A is the first one
+	var mats [][][]float64
+	if *flagMatrices != "" {
+		for _, path := range strings.Split(*flagMatrices, ",") {
+			mats = append(mats, ReadMatrix(path))
+		}
+		A = mats[0]
+	}
+
+	// PCA: A is the plaintext covariance until the encrypted one replaces it
+	var X [][]float64
+	var means []float64
+	if *flagPCA != "" {
+		if len(mats) > 0 {
+			panic(fmt.Errorf("-pca does not support -matrices"))
+		}
+		X = ReadMatrix(*flagPCA)
+		A = Covariance(X)
+		means = ColumnMeans(X)
+	}
+	if (*flagProject != "" || *flagReconstruct || *flagVariance > 0) && len(mats) > 0 {
+		panic(fmt.Errorf("-project, -reconstruct and -variance do not support -matrices"))
+	}
+	if *flagVariance > 0 {
+		if *flagSpectrum != SpectrumLargest || *flagStarts > 1 {
+			panic(fmt.Errorf("-variance only supports the largest spectrum with a single start vector"))
+		}
+		if *flagDeflation != DeflationProjection {
+			panic(fmt.Errorf("-variance needs -deflation projection, hotelling decrypts the deflated matrix after every eigenpair"))
+		}
+		switch *flagVarianceReveal {
+		case VarianceRevealBit:
+		case VarianceRevealRatio:
+			if *flagPrecondition != PreconditionTrace {
+				panic(fmt.Errorf("-variance-reveal ratio needs -precondition trace to bring tr(A) into the range of HomomoNewton"))
+			}
+		default:
+			panic(fmt.Errorf("unknown -variance-reveal %q, expected bit or ratio", *flagVarianceReveal))
+		}
+	}
+
+	// Preconditioning by public factors, undone on the decrypted eigenvalues.
+	// inputA and inputMats keep the matrices as read.
+	inputA, inputMats := A, mats
+	precondition := PreconditionFactor(A, *flagPrecondition)
+	A = ScaleMatrix(A, precondition)
+	if X != nil {
+		X = ScaleMatrix(X, math.Sqrt(precondition))
+	}
+	var preconditions []float64
+	mats = nil
+	for _, M := range inputMats {
+		preconditions = append(preconditions, PreconditionFactor(M, *flagPrecondition))
+		mats = append(mats, ScaleMatrix(M, preconditions[len(preconditions)-1]))
+	}
+	if *flagPrecondition != PreconditionNone {
+		fmt.Printf("Preconditioning A by c = %v (%s)\n", precondition, *flagPrecondition)
+		for k := 1; k < len(mats); k++ {
+			fmt.Printf("Preconditioning matrix %d by c = %v (%s)\n", k, preconditions[k], *flagPrecondition)
+		}
+	}
+
+	// Sparse packing: only the slots of the padded problem are encoded and bootstrapped
+	LogSlots := params.LogMaxSlots()
+	if *flagSparse {
+		LogSlots = SparseLogSlots(params, len(A), *flagStarts, MultiStartStride(len(A)), len(mats))
+	}
+	if X != nil {
+		LogSlots = max(LogSlots, PCALogSlots(params, len(X), len(A)))
+	}
+	Slots := 1 << LogSlots
+	for k, M := range mats {
+		if len(M) != len(A) {
+			panic(fmt.Errorf("matrix %d is %d x %d, expected %d x %d like matrix 0", k, len(M), len(M), len(A), len(A)))
+		}
+	}
+	if len(mats)*MatrixStride(len(A)) > Slots {
+		panic(fmt.Errorf("%d matrices of stride %d do not fit in %d slots", len(mats), MatrixStride(len(A)), Slots))
+	}
 
 	// ==================================
 	// 2. BOOTSTRAPPING PARAMETERSLITERAL
@@ -56,6 +195,8 @@ func main() {
 
 		LogN: utils.Pointy(LogN),
 
+		LogSlots: utils.Pointy(LogSlots),
+
 		LogP: []int{61, 61, 61, 61},
 
 		Xs: params.Xs(),
@@ -79,6 +220,9 @@ func main() {
 	fmt.Println()
 	fmt.Println("1. Generating ckks keys...")
 	kgen := rlwe.NewKeyGenerator(params)
+	if *flagSeed != 0 {
+		kgen = NewSeededKeyGenerator(params, *flagSeed, "keys")
+	}
 	//fmt.Printf("LogQP <= 438: %v\n", params.LogQP())
 
 	// Secret Key
@@ -97,8 +241,10 @@ func main() {
 	// Bootstrapping evaluation key
 	fmt.Println()
 	fmt.Println("2. Generating bootstrapping evaluation keys...")
-	btpEvk, _, err := btpParams.GenEvaluationKeys(sk)
-	if err != nil {
+	var btpEvk *bootstrapping.EvaluationKeys
+	if *flagSeed != 0 {
+		btpEvk = SeededBootstrappingKeys(btpParams, sk, *flagSeed)
+	} else if btpEvk, _, err = btpParams.GenEvaluationKeys(sk); err != nil {
 		panic(err)
 	}
 	fmt.Println("Done")
@@ -117,38 +263,11 @@ func main() {
 	ecd := ckks.NewEncoder(ckks.Parameters(params))
 	// Encryptor
 	enc := rlwe.NewEncryptor(params, pk)
-	dec := rlwe.NewDecryptor(params, sk)
-	eval := ckks.NewEvaluator(params, evk)
-
-	//file, err := os.Open("data/Yale_left.csv")
-	//file, err := os.Open("data/Yale_right.csv")
-	//file, err := os.Open("data/Air_left.csv")
-	//file, err := os.Open("data/Air_right.csv")
-	//file, err := os.Open("data/wine_left.csv")
-	file, err := os.Open("data/wine_right.csv")
-	if err != nil {
-		panic(err)
-	}
-	defer file.Close()
-
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
-	if err != nil {
-		panic(err)
-	}
-	//
-	var A [][]float64
-	for _, row := range records {
-		var floatRow []float64
-		for _, val := range row {
-			f, err := strconv.ParseFloat(val, 64)
-			if err != nil {
-				panic(err)
-			}
-			floatRow = append(floatRow, f)
-		}
-		A = append(A, floatRow)
+	if *flagSeed != 0 {
+		enc = NewSeededEncryptor(params, pk, *flagSeed, "encryption")
 	}
+	dec := rlwe.NewDecryptor(params, sk)
+	var eval tracer.Evaluator = ckks.NewEvaluator(params, evk)
 
 	//A := [][]float64{
 	//	{1.0, 2.0, 3.0, 4.0},
@@ -186,12 +305,16 @@ func main() {
 	var rowA []float64
 
 	
-	for _, row := range A {
+	rows := A
+	if X != nil {
+		rows = X
+	}
+	for _, row := range rows {
 		rowA = append(rowA, row...)
 	}
 	//fmt.Println(rowA)
 
-	ptRowA := ckks.NewPlaintext(params, params.MaxLevel())
+	ptRowA := NewSlotsPlaintext(params, params.MaxLevel(), Slots)
 	if err = ecd.Encode(rowA, ptRowA); err != nil {
 		panic(err)
 	}
@@ -209,7 +332,55 @@ func main() {
 	batch := 1
 	//d := 5 // Number of Newton iteration
 	d := 6
-	evalInnsum := eval.WithKey(rlwe.NewMemEvaluationKeySet(rlk, kgen.GenGaloisKeysNew(params.GaloisElementsForInnerSum(batch, n), sk)...))
+
+	starts := *flagStarts
+	stride := MultiStartStride(n)
+
+	// Galois keys of the whole pipeline, generated once
+	fmt.Println()
+	fmt.Println("Generating pipeline Galois keys...")
+	projectComponents := 0
+	if *flagProject != "" {
+		projectComponents = n
+	}
+	galEls := PipelineGaloisElements(params, PipelineOptions{
+		Slots:       Slots,
+		N:           n,
+		Batch:       batch,
+		Starts:      starts,
+		Matrices:    len(mats),
+		Square:      *flagSpectrum == SpectrumNearest,
+		PCARows:     len(X),
+		Project:     projectComponents,
+		Reconstruct: *flagReconstruct,
+		Variance:    *flagVariance > 0,
+	})
+	eval = NewPipelineEvaluator(params, galEls, kgen, rlk, sk)
+	fmt.Println("Done")
+
+	// Operation tracer, printed and written when main returns
+	if *flagTraceOps != "" {
+		ops := tracer.NewTracer(tracer.Unwrap(eval), ecd, dec)
+		eval = ops
+		defer func() {
+			fmt.Println()
+			fmt.Println("Evaluator operations...")
+			ops.PrintSummary()
+			ops.WriteJSON(*flagTraceOps)
+			fmt.Printf("The operation trace has been written to %s\n", *flagTraceOps)
+		}()
+	}
+
+	// PCA: centering, total variance and covariance of the encrypted data
+	var ctTotalVar *rlwe.Ciphertext
+	if X != nil {
+		fmt.Println()
+		fmt.Printf("Computing the covariance of the %d x %d encrypted data...", len(X), n)
+		ctCentered := HomomoCenter(ctRowA, eval, len(X), n, params, ecd)
+		ctTotalVar = HomomoTotalVariance(ctCentered, eval, len(X), n, params, ecd)
+		ctRowA = HomomoCovariance(ctCentered, eval, len(X), n, params, ecd, *flagWorkers)
+		A = DecryptMatrix(ctRowA, n, dec, ecd, Slots)
+	}
 
 	a := []float64{-0.00013651433183402268}
 	b := []float64{0.13651433183402267}
@@ -217,11 +388,11 @@ func main() {
 	f1 := []float64{0.5}
 	f2 := []float64{1.5}
 
-	pta := ckks.NewPlaintext(params, params.MaxLevel())
-	ptb := ckks.NewPlaintext(params, params.MaxLevel())
+	pta := NewSlotsPlaintext(params, params.MaxLevel(), Slots)
+	ptb := NewSlotsPlaintext(params, params.MaxLevel(), Slots)
 
-	ptf1 := ckks.NewPlaintext(params, params.MaxLevel())
-	ptf2 := ckks.NewPlaintext(params, params.MaxLevel())
+	ptf1 := NewSlotsPlaintext(params, params.MaxLevel(), Slots)
+	ptf2 := NewSlotsPlaintext(params, params.MaxLevel(), Slots)
 
 	if err = ecd.Encode(a, pta); err != nil {
 		panic(err)
@@ -250,16 +421,6 @@ func main() {
 	//	panic(err)
 	//}
 
-	rot := -1
-	galElsRot := []uint64{
-		// The galois element for the cyclic rotations by 1 positions to the right.
-		params.GaloisElement(rot)}
-	rotEval := eval.WithKey(rlwe.NewMemEvaluationKeySet(rlk, kgen.GenGaloisKeysNew(galElsRot, sk)...))
-
-	rot1 := -n
-	galElsRot1 := []uint64{
-		params.GaloisElement(rot1)}
-	rotEval1 := eval.WithKey(rlwe.NewMemEvaluationKeySet(rlk, kgen.GenGaloisKeysNew(galElsRot1, sk)...))
 
 	//start := time.Now()
 	//ctEigenVec, ctEigenVal := HomomoPowerMethod(evalInnsum, lt, ltEval,
@@ -292,7 +453,6 @@ func main() {
 	//}
 	//fmt.Printf("...\n")
 	//
-	ptVector := ckks.NewPlaintext(params, params.MaxLevel())
 	//
 	//vec00 := make([]float64, Slots)
 	//ptVec00 := ckks.NewPlaintext(params, params.MaxLevel())
@@ -347,26 +507,225 @@ func main() {
 	//	fmt.Println(row)
 	//}
 
+	// Multi-start: several start vectors iterated in disjoint slot blocks
+	var ptaBlocks, ptbBlocks, ptf1Blocks, ptf2Blocks *rlwe.Plaintext
+	var cmpEval *comparison.Evaluator
+	if starts > 1 {
+		if 2*starts*stride > Slots {
+			panic(fmt.Errorf("%d start vectors of stride %d do not fit in %d slots", starts, stride, Slots))
+		}
+		ptaBlocks = BlockPlaintext(a[0], 1, starts, stride, Slots, params, ecd)
+		ptbBlocks = BlockPlaintext(b[0], 1, starts, stride, Slots, params, ecd)
+		ptf1Blocks = BlockPlaintext(f1[0], 1, starts, stride, Slots, params, ecd)
+		ptf2Blocks = BlockPlaintext(f2[0], 1, starts, stride, Slots, params, ecd)
+		cmpEval = NewComparisonEvaluator(params, eval, btpEval)
+	}
+
+	// ptOne masks slot 0, ptShift also scales the Rayleigh quotient by the shift factor
+	ptOne := NewSlotsPlaintext(params, params.MaxLevel(), Slots)
+	if err = ecd.Encode([]float64{1}, ptOne); err != nil {
+		panic(err)
+	}
+	var ptShift *rlwe.Plaintext
+	if *flagRayleigh {
+		if starts > 1 {
+			panic(fmt.Errorf("-rayleigh cannot be combined with -starts"))
+		}
+		if *flagRayleighShift < 0 || *flagRayleighShift >= RayleighMaxShift {
+			panic(fmt.Errorf("-rayleigh-shift %v is outside [0, %v)", *flagRayleighShift, RayleighMaxShift))
+		}
+		if *flagRayleighShift != 0 {
+			ptShift = NewSlotsPlaintext(params, params.MaxLevel(), Slots)
+			if err = ecd.Encode([]float64{*flagRayleighShift}, ptShift); err != nil {
+				panic(err)
+			}
+		}
+	}
+
+	if *flagIndefinite && (starts > 1 || *flagRayleigh) {
+		panic(fmt.Errorf("-indefinite cannot be combined with -starts or -rayleigh"))
+	}
+
+	// Projection deflation: A stays fixed and the iterates are kept orthogonal
+	// to the eigenvectors found so far
+	deflation := *flagDeflation
+	if deflation != DeflationHotelling && deflation != DeflationProjection {
+		panic(fmt.Errorf("unknown deflation mode %q", deflation))
+	}
+	if deflation == DeflationProjection && (starts > 1 || *flagRayleigh || *flagIndefinite) {
+		panic(fmt.Errorf("-deflation projection cannot be combined with -starts, -rayleigh or -indefinite"))
+	}
+	var ctDeflVecs []*rlwe.Ciphertext
+
+	if *flagPrecision != "" && (starts > 1 || *flagRayleigh || *flagIndefinite || len(mats) > 1) {
+		panic(fmt.Errorf("-precision cannot be combined with -starts, -rayleigh, -indefinite or -matrices"))
+	}
+
+	if len(mats) > 1 && (starts > 1 || *flagRayleigh || *flagIndefinite || deflation != DeflationHotelling ||
+		*flagSpectrum != SpectrumLargest) {
+		panic(fmt.Errorf("-matrices only supports the largest spectrum with hotelling deflation"))
+	}
+
+	// Preconditioned input matrix, before the spectral shift and the deflations, for the reference
+	origA := A
+	ctOrigRowA := ctRowA
+
+	// Spectrum mode, with the target and the bound given for the input matrix
+	spectrum := *flagSpectrum
+	target := *flagTarget * precondition
+	bound := *flagBound * precondition
+	if spectrum == SpectrumNearest {
+		bound *= precondition
+	}
+	if spectrum != SpectrumLargest && bound == 0 {
+		bound = GershgorinBound(A)
+		if spectrum == SpectrumNearest {
+			bound = math.Pow(bound+math.Abs(target), 2)
+		}
+	}
+	var ptBound *rlwe.Plaintext
+	var ltOrig lintrans.LinearTransformation
+	var ltEvalOrig *lintrans.Evaluator
+	if spectrum != SpectrumLargest {
+		fmt.Println()
+		fmt.Printf("Shifting the spectrum (%s, c = %v)...\n", spectrum, bound)
+
+		ptBound = NewSlotsPlaintext(params, params.MaxLevel(), Slots)
+		if err = ecd.Encode([]float64{bound}, ptBound); err != nil {
+			panic(err)
+		}
+		if spectrum == SpectrumNearest {
+			ltOrig, ltEvalOrig = LinearTrans(A, Slots, n, ctRowA, params, ecd, eval)
+		}
+
+		vecZero := make([]float64, Slots)
+		ptVecZero := NewSlotsPlaintext(params, params.MaxLevel(), Slots)
+		if err = ecd.Encode(vecZero, ptVecZero); err != nil {
+			panic(err)
+		}
+		ctVecZero, err := enc.EncryptNew(ptVecZero)
+		if err != nil {
+			panic(err)
+		}
+
+		ctRowA = HomomoSpectralShift(spectrum, ctRowA, bound, target, eval, n, batch,
+			params, ctVecZero, ecd, *flagWorkers)
+		A = DecryptMatrix(ctRowA, n, dec, ecd, Slots)
+	}
+
+	// Matrix the power method iterates with
+	iterA := A
+
+	// Replicated inputs of the matrix-vector products, cached between products
+	rep := NewReplicator(n, MatVecReplicas(Slots, n))
+	repBlocks := NewReplicator(n, 2)
+
+	// Level budget of the power method, bootstrappings placed by EnsureLevel
+	fmt.Println()
+	fmt.Println("Level plan of the power method per eigenpair...")
+	shift := deflation != DeflationProjection
+	switch {
+	case len(mats) > 1:
+		PlanLevels(params.MaxLevel(), btpEval.OutputLevel(), PowerMethodStages(max_iter, false, true)).Print(d)
+	case starts > 1:
+		// HomomoSelectBest bootstraps the winner before the shift
+		PlanLevels(params.MaxLevel(), btpEval.OutputLevel(), PowerMethodStages(max_iter, false, false)).Print(d)
+	case *flagRayleigh:
+		PlanLevels(params.MaxLevel(), btpEval.OutputLevel(), RayleighStages(max_iter, ptShift != nil, true)).Print(d)
+	case *flagIndefinite:
+		PlanLevels(params.MaxLevel(), btpEval.OutputLevel(), SignedStages(max_iter, true)).Print(d)
+	default:
+		if !shift {
+			fmt.Printf("%2sfor the 1-th eigenpair:\n", "")
+		}
+		PlanLevels(params.MaxLevel(), btpEval.OutputLevel(), PowerMethodStages(max_iter, false, shift)).Print(d)
+		if !shift {
+			fmt.Printf("%2sfrom the 2-th eigenpair, with projection deflation:\n", "")
+			PlanLevels(params.MaxLevel(), btpEval.OutputLevel(), PowerMethodStages(max_iter, true, shift)).Print(d)
+		}
+	}
+
+	// Number of eigenpairs, an upper bound with the explained-variance stopping
 	lE := 4
+	if *flagVariance > 0 {
+		lE = n
+	}
+
+	if len(mats) > 1 {
+		start := time.Now()
+		singularVecs, singularVals := HomomoBatchSVD(mats, lE, Slots, max_iter, batch, d, a[0], b[0], f1[0], f2[0],
+			params, ecd, enc, dec, eval, btpEval, *flagWorkers, *flagSeed)
+		fmt.Println()
+		fmt.Printf("The times of SVD: %v\n", time.Since(start))
+
+		for k := range mats {
+			singularVals[k] = scale(singularVals[k], 1/preconditions[k])
+		}
+		for k := range mats {
+			WriteSVD(fmt.Sprintf("result/output_%d.csv", k), singularVecs[k], singularVals[k])
+		}
+		if *flagReport {
+			for k := range mats {
+				startVecs := make([][]float64, lE)
+				for i := range startVecs {
+					startVecs[i] = StartVectors(*flagSeed, i, len(mats), n)[k]
+				}
+				refVecs, refVals := ReferenceSVD(mats[k], startVecs, max_iter, false, d, a[0], b[0], f1[0], f2[0])
+				fmt.Println()
+				fmt.Printf("Matrix %d:", k)
+				PrintAccuracyReport(inputMats[k], singularVecs[k], singularVals[k], refVecs,
+					scale(refVals, 1/preconditions[k]), spectrum, *flagTarget)
+			}
+		}
+		fmt.Println("The CSV files have been successfully generated!")
+		return
+	}
 	singularVec := make([][]float64, n)
 	singularVal := make([]float64, n)
+	startVecs := make([][]float64, lE)
+	var ctComponents []*rlwe.Ciphertext
+	var ctRowApprox *rlwe.Ciphertext
+
+	// Client-side precision diagnostics, decrypting every stage
+	var trace *PrecisionTrace
+	if *flagPrecision != "" {
+		trace = NewPrecisionTrace(params, ecd, dec, Slots, n, d, a[0], b[0], f1[0], f2[0])
+		trace.Precondition = precondition
+	}
+	// Explained-variance stopping: encrypted trace and running sum of the eigenvalues
+	var ctTrace, ctInvTrace, ctEigenSum *rlwe.Ciphertext
+	var ctOrigEigenVals []*rlwe.Ciphertext
+	var varianceScale float64
+	if *flagVariance > 0 {
+		ctTrace = ctTotalVar
+		if ctTrace == nil {
+			ctTrace = HomomoTrace(ctOrigRowA, eval, n, params, ecd)
+		}
+		if *flagVarianceReveal == VarianceRevealRatio {
+			ctInvTrace = HomomoInverse(ctTrace, eval, ptf1, ptf2, pta, ptb, btpEval, d)
+		} else {
+			cmpEval = NewComparisonEvaluator(params, eval, btpEval)
+			// |sum - fraction*tr| is bounded by sqrt(n)*||A||_F
+			varianceScale = 1 / (2 * math.Sqrt(float64(n)*frobenius2(origA)))
+		}
+	}
 	start := time.Now()
 	for i := 0; i < lE; i++ {
 		fmt.Println()
 		fmt.Printf("the %d-th iteration...", i+1)
 
 		// Generate random vector
-		r := rand.New(rand.NewSource(int64(i + 5)))
-		arr := make([]float64, n)
-		for i := 0; i < n; i++ {
-			arr[i] = 2*r.Float64() - 1
+		vecs := StartVectors(*flagSeed, i, starts, n)
+		startVecs[i] = vecs[0]
+		vec := vecs[0]
+		if starts > 1 {
+			vec = EncodeBlocks(vecs, Slots, stride)
 		}
-		vec := arr
 		//vec := []float64{1.0, 0.0, 3.0, 2.0}
 		fmt.Println()
-		fmt.Println("the generated random vector:", vec)
+		fmt.Println("the generated random vector:", vecs)
 
-		ptVec := ckks.NewPlaintext(params, params.MaxLevel())
+		ptVec := NewSlotsPlaintext(params, params.MaxLevel(), Slots)
 		if err = ecd.Encode(vec, ptVec); err != nil {
 			panic(err)
 		}
@@ -376,116 +735,263 @@ func main() {
 		}
 
 		
-		vec0 := make([]float64, Slots)
-		ptVec0 := ckks.NewPlaintext(params, params.MaxLevel())
-		if err = ecd.Encode(vec0, ptVec0); err != nil {
-			panic(err)
-		}
-		ctVec0, err := enc.EncryptNew(ptVec0)
-		if err != nil {
-			panic(err)
-		}
-		vec00 := make([]float64, Slots)
-		ptVec00 := ckks.NewPlaintext(params, params.MaxLevel())
-		if err = ecd.Encode(vec00, ptVec00); err != nil {
-			panic(err)
+		var ctLintransVec, ctEigenVec, ctEigenVal *rlwe.Ciphertext
+		if starts > 1 {
+			lt, ltEval := LinearTransBlocks(A, Slots, n, starts, stride, ctVec, params, ecd, eval)
+
+			ctMultiVec, ctMultiVal, ctMultiRes := HomomoPowerMethodMultiStart(lt, ltEval, ctVec, eval, max_iter, batch, n,
+				ptf1Blocks, ptf2Blocks, ptaBlocks, ptbBlocks, btpEval, d, repBlocks)
+
+			// The residuals are bounded by ||Av||^2, at most the squared Frobenius norm of the current matrix
+			frob := 0.0
+			for _, row := range A {
+				for _, val := range row {
+					frob += val * val
+				}
+			}
+			cmpScale := 1 / (2 * frob)
+
+			ctEigenVec, ctEigenVal = HomomoSelectBest(ctMultiVec, ctMultiVal, ctMultiRes, cmpEval, eval, btpEval, n,
+				starts, stride, cmpScale, Slots, params, ecd)
+		} else if *flagRayleigh {
+			lt, ltEval := LinearTrans(A, Slots, n, ctVec, params, ecd, eval)
+
+			var emit func(int, *rlwe.Ciphertext, *rlwe.Ciphertext)
+			if *flagRayleighEmit {
+				emit = func(iter int, _ *rlwe.Ciphertext, ctQuotient *rlwe.Ciphertext) {
+					fmt.Printf("%2sRayleighQuotient of the %d-th iterate: %20.15f\n", "", iter,
+						DecryptVector(ctQuotient, 1, dec, ecd, Slots)[0])
+				}
+			}
+
+			fmt.Println()
+			fmt.Println("3. Performing homomorphic shifted power method...")
+			ctLintransVec, ctEigenVec, ctEigenVal = HomomoRayleighPowerMethod(lt, ltEval,
+				ctVec, eval, max_iter, batch, n, ptf1, ptf2, pta, ptb, ptOne, ptShift, btpEval, d, rep, emit)
+		} else if *flagIndefinite {
+			lt, ltEval := LinearTrans(A, Slots, n, ctVec, params, ecd, eval)
+
+			ctLintransVec, ctEigenVec, ctEigenVal = HomomoSignedPowerMethod(lt, ltEval,
+				ctVec, eval, max_iter, batch, n, ptf1, ptf2, pta, ptb, btpEval, d, rep)
+		} else {
+			lt, ltEval := LinearTrans(A, Slots, n, ctVec, params, ecd, eval)
+			if trace != nil {
+				trace.Eigenpair, trace.A = i+1, A
+			}
+			if len(ctDeflVecs) > 0 {
+				fmt.Println()
+				fmt.Printf("%2sprojecting every iterate out of the %d eigenvectors found\n", "", len(ctDeflVecs))
+			}
+
+			ctLintransVec, ctEigenVec, ctEigenVal = HomomoPowerMethod(lt, ltEval,
+				ctVec, eval, dec, ecd, Slots, max_iter, batch, n, ptf1, ptf2, pta, ptb, btpEval, d, rep,
+				ctDeflVecs, ptOne, *flagWorkers, trace)
 		}
-		ctVec00, err := enc.EncryptNew(ptVec00)
-		if err != nil {
-			panic(err)
+
+		ctComponents = append(ctComponents, ctEigenVec)
+
+		// Eigenvalue of the original matrix, the deflation works on B
+		ctOrigEigenVal := ctEigenVal
+		switch spectrum {
+		case SpectrumSmallest:
+			ctOrigEigenVal = HomomoSmallestEigenVal(ctEigenVal, ptBound, eval)
+		case SpectrumNearest:
+			ctLintransOrig := HomomoMatMutiVec(ltOrig, ltEvalOrig, ctEigenVec, eval, rep)
+			ctOrigEigenVal = HomomoEigenVal(ctLintransOrig, ctEigenVec, eval, batch, n, ptf1, ptf2, pta, ptb, btpEval, d)
 		}
 
-		vec000 := make([]float64, Slots)
-		ptVec000 := ckks.NewPlaintext(params, params.MaxLevel())
-		if err = ecd.Encode(vec000, ptVec000); err != nil {
-			panic(err)
+		if deflation == DeflationProjection {
+			ctDeflVecs = append(ctDeflVecs, HomomoDeflationVec(ctEigenVec, btpEval))
+		} else {
+			ctShiftMat, ctRankOne := HomomoEigenShift(ctRowA, ctEigenVec, ctEigenVal, eval, n, batch, params, ecd, btpEval,
+				*flagWorkers, trace)
+
+			A = DecryptMatrix(ctShiftMat, n, dec, ecd, Slots)
+			ctRowA = ctShiftMat
+
+			// The deflation of A itself subtracts the term of the reconstruction
+			if *flagReconstruct && spectrum == SpectrumLargest {
+				ctRowApprox = HomomoAccumulate(ctRowApprox, ctRankOne, eval)
+			}
 		}
-		ctVec000, err := enc.EncryptNew(ptVec00)
-		if err != nil {
-			panic(err)
+		if *flagReconstruct && (deflation == DeflationProjection || spectrum != SpectrumLargest) {
+			_, ctRankOne := HomomoRankOne(ctEigenVec, ctOrigEigenVal, eval, n, batch, params, ecd, btpEval, *flagWorkers)
+			ctRowApprox = HomomoAccumulate(ctRowApprox, ctRankOne, eval)
 		}
 
-		lt, ltEval := LinearTrans(A, Slots, n, ctVec, params, ecd, eval, kgen, rlk, sk)
+		// With -variance the eigenpairs stay encrypted until the loop ends
+		if ctLintransVec != nil && *flagVariance == 0 {
+			LintransVec := dec.DecryptNew(ctLintransVec)
+			LintransVecList := make([]float64, Slots)
+			if err = ecd.Decode(LintransVec, LintransVecList); err != nil {
+				panic(err)
+			}
 
-		ctLintransVec, ctEigenVec, ctEigenVal := HomomoPowerMethod(evalInnsum, lt, ltEval,
-			ctVec, eval, dec, ecd, Slots, max_iter, batch, n, ptf1, ptf2, pta, ptb, btpEval, d, ctVec0, LogN, rotEval, rotEval1, rot, rot1)
+			fmt.Printf("%2sLintransVec: ", "")
+			for i := 0; i < 5; i++ {
+				fmt.Printf("%20.15f ", LintransVecList[i])
+			}
+			fmt.Printf("...\n")
+		}
 
-		ctShiftMat := HomomoEigenShift(ctRowA, ctEigenVec, ctEigenVal, rotEval, rot, ptVector, eval, n, evalInnsum, batch, params, kgen, rlk, sk, ctVec0, ctVec00, ctVec000, ecd)
+		if *flagVariance > 0 {
+			ctOrigEigenVals = append(ctOrigEigenVals, ctOrigEigenVal)
+		} else {
+			eigenVec := dec.DecryptNew(ctEigenVec)
+			eigenVecList := make([]float64, Slots)
+			if err = ecd.Decode(eigenVec, eigenVecList); err != nil {
+				panic(err)
+			}
 
-		ptShiftMat := dec.DecryptNew(ctShiftMat)
-		shiftMatVec := make([]float64, Slots)
-		if err = ecd.Decode(ptShiftMat, shiftMatVec); err != nil {
-			panic(err)
-		}
+			fmt.Printf("%2sEigenVector: ", "")
+			for i := 0; i < 5; i++ {
+				fmt.Printf("%20.15f ", eigenVecList[i])
+			}
+			fmt.Printf("...\n")
 
-		shiftMat := make([][]float64, n)
-		for i := 0; i < n; i++ {
-			shiftMat[i] = make([]float64, n)
-		}
+			singularVec[i] = eigenVecList[:n]
 
-		
-		nPow := math.Pow(float64(n), 2)
-		for i := 0; i < int(nPow); i++ {
-			row := i / n
-			col := i % n
-			shiftMat[row][col] = shiftMatVec[i]
-		}
+			fmt.Printf("%2sSingularVec: ", "")
+			fmt.Println(singularVec)
+
+			eigenVal := dec.DecryptNew(ctOrigEigenVal)
+			eigenValList := make([]float64, Slots)
+			if err = ecd.Decode(eigenVal, eigenValList); err != nil {
+				panic(err)
+			}
 
-		A = shiftMat
-		ctRowA = ctShiftMat
+			fmt.Printf("%2sEigenValue: ", "")
+			for i := 0; i < 5; i++ {
+				fmt.Printf("%20.15f ", eigenValList[i])
+			}
+			fmt.Printf("...\n")
 
-		LintransVec := dec.DecryptNew(ctLintransVec)
-		LintransVecList := make([]float64, Slots)
-		if err = ecd.Decode(LintransVec, LintransVecList); err != nil {
-			panic(err)
+			singularVal[i] = eigenValList[0] / precondition
+			fmt.Printf("%2sSingularVal: ", "")
+			fmt.Println(singularVal)
 		}
 
-		fmt.Printf("%2sLintransVec: ", "")
-		for i := 0; i < 5; i++ {
-			fmt.Printf("%20.15f ", LintransVecList[i])
+		// Only the comparison bit or the ratio is decrypted
+		if *flagVariance > 0 {
+			ctEigenSum = HomomoAccumulate(ctEigenSum, ctOrigEigenVal, eval)
+			var reached bool
+			if *flagVarianceReveal == VarianceRevealRatio {
+				ratio := DecryptVector(HomomoVarianceRatio(ctEigenSum, ctInvTrace, eval), 1, dec, ecd, Slots)[0]
+				fmt.Printf("%2sExplained variance: %.4f%%\n", "", 100*ratio)
+				reached = ratio >= *flagVariance
+			} else {
+				ctReached := HomomoVarianceReached(ctEigenSum, ctTrace, *flagVariance, varianceScale, cmpEval, eval)
+				reached = DecryptVector(ctReached, 1, dec, ecd, Slots)[0] > 0.5
+				fmt.Printf("%2sExplained variance of %v reached: %v\n", "", *flagVariance, reached)
+			}
+			if reached {
+				lE = i + 1
+				break
+			}
 		}
-		fmt.Printf("...\n")
+	}
+	elapsed := time.Since(start)
+	fmt.Println()
+	fmt.Printf("The times of SVD: %v\n", elapsed)
 
-		eigenVec := dec.DecryptNew(ctEigenVec)
-		eigenVecList := make([]float64, Slots)
-		if err = ecd.Decode(eigenVec, eigenVecList); err != nil {
-			panic(err)
+	if *flagVariance > 0 {
+		for i, ctOrigEigenVal := range ctOrigEigenVals[:lE] {
+			singularVal[i] = DecryptVector(ctOrigEigenVal, 1, dec, ecd, Slots)[0] / precondition
+			singularVec[i] = DecryptVector(ctComponents[i], n, dec, ecd, Slots)
 		}
+		fmt.Printf("%2sSingularVec: ", "")
+		fmt.Println(singularVec[:lE])
+		fmt.Printf("%2sSingularVal: ", "")
+		fmt.Println(singularVal[:lE])
+	}
+
+	if trace != nil {
+		trace.WriteJSON(*flagPrecision)
+		fmt.Printf("The precision trace has been written to %s\n", *flagPrecision)
+	}
 
-		fmt.Printf("%2sEigenVector: ", "")
-		for i := 0; i < 5; i++ {
-			fmt.Printf("%20.15f ", eigenVecList[i])
+	if *flagReport {
+		refVecs, refVals := ReferenceSVD(iterA, startVecs[:lE], max_iter, *flagIndefinite, d, a[0], b[0], f1[0], f2[0])
+		// Eigenvalues of the original matrix, as for ctOrigEigenVal
+		for i := range refVals {
+			switch spectrum {
+			case SpectrumSmallest:
+				refVals[i] = bound - refVals[i]
+			case SpectrumNearest:
+				refVals[i] = ReferenceEigenVal(origA, refVecs[i], d, a[0], b[0], f1[0], f2[0])
+			}
 		}
-		fmt.Printf("...\n")
+		refVals = scale(refVals, 1/precondition)
+		PrintAccuracyReport(inputA, singularVec[:lE], singularVal[:lE], refVecs, refVals, spectrum, *flagTarget)
+	}
 
-		
-		singularVec[i] = eigenVecList[:n]
+	
+	if ctTotalVar != nil {
+		totalVar := DecryptVector(ctTotalVar, 1, dec, ecd, Slots)[0] / precondition
+		fmt.Println()
+		fmt.Println("Explained variance of the principal components...")
+		PrintExplainedVariance(singularVal[:lE], totalVar)
+		WriteExplainedVariance("result/explained_variance.csv", singularVal[:lE], totalVar)
+	}
 
-		fmt.Printf("%2sSingularVec: ", "")
-		fmt.Println(singularVec)
+	// Low-rank reconstruction of the input, undone from the preconditioning
+	if ctRowApprox != nil {
+		residual2 := DecryptVector(HomomoResidualNorm2(ctOrigRowA, ctRowApprox, eval, n, params),
+			1, dec, ecd, Slots)[0] / (precondition * precondition)
+		approx := ScaleMatrix(DecryptMatrix(ctRowApprox, n, dec, ecd, Slots), 1/precondition)
+		fmt.Println()
+		fmt.Printf("Low-rank reconstruction (rank %d)...\n", lE)
+		fmt.Printf("%2sencrypted squared residual ||A - A_k||_F^2: %.6e, relative %.3e\n", "", residual2,
+			residual2/frobenius2(inputA))
+		fmt.Printf("%2splaintext squared residual of the decrypted A_k: %.6e\n", "",
+			frobenius2(subMatrix(inputA, approx)))
+		WriteReconstruction("result/reconstruction.csv", approx)
+		fmt.Println("The reconstruction has been written to result/reconstruction.csv")
+	}
 
-		eigenVal := dec.DecryptNew(ctEigenVal)
-		eigenValList := make([]float64, Slots)
-		if err = ecd.Decode(eigenVal, eigenValList); err != nil {
-			panic(err)
+	// Projection of new samples, centered by the means of the PCA data
+	if *flagProject != "" {
+		samples := ReadMatrix(*flagProject)
+		for _, row := range samples {
+			for j := range means {
+				row[j] -= means[j]
+			}
 		}
 
-		fmt.Printf("%2sEigenValue: ", "")
-		for i := 0; i < 5; i++ {
-			fmt.Printf("%20.15f ", eigenValList[i])
+		fmt.Println()
+		fmt.Printf("Projecting %d new samples onto %d components...\n", len(samples), lE)
+		var scores [][]float64
+		for first := 0; first < len(samples); first += Slots / stride {
+			chunk := samples[first:min(first+Slots/stride, len(samples))]
+			ptSamples := NewSlotsPlaintext(params, params.MaxLevel(), Slots)
+			if err = ecd.Encode(EncodeBlocks(chunk, Slots, stride), ptSamples); err != nil {
+				panic(err)
+			}
+			ctSamples, err := enc.EncryptNew(ptSamples)
+			if err != nil {
+				panic(err)
+			}
+
+			var ctScores *rlwe.Ciphertext
+			if *flagProjectPlain {
+				ctScores = HomomoProject(singularVec[:lE], ctSamples, eval, n, Slots, params, ecd)
+			} else {
+				ctScores = HomomoProjectEncrypted(ctComponents, ctSamples, eval, n, Slots, params, ecd, *flagWorkers)
+			}
+			scores = append(scores, DecryptScores(ctScores, len(chunk), lE, stride, dec, ecd, Slots)...)
 		}
-		fmt.Printf("...\n")
 
-		
-		singularVal[i] = eigenValList[0]
-		fmt.Printf("%2sSingularVal: ", "")
-		fmt.Println(singularVal)
+		maxErr := 0.0
+		for s, row := range samples {
+			for i := 0; i < lE; i++ {
+				maxErr = math.Max(maxErr, math.Abs(scores[s][i]-dot(singularVec[i], row)))
+			}
+		}
+		fmt.Printf("%2smax |score - plaintext score|: %.3e\n", "", maxErr)
+		WriteScores("result/scores.csv", scores)
+		fmt.Println("The scores have been written to result/scores.csv")
 	}
-	elapsed := time.Since(start)
-	fmt.Println()
-	fmt.Printf("The times of SVD: %v\n", elapsed)
 
-	
 	file, err = os.Create("result/output.csv")
 	if err != nil {
 		panic(err)
@@ -508,3 +1014,34 @@ func main() {
 
 	fmt.Println("The CSV file has been successfully generated!")
 }
+
+// DecryptVector decrypts the first n slots of ctVec.
+func DecryptVector(ctVec *rlwe.Ciphertext, n int, dec *rlwe.Decryptor, ecd *ckks.Encoder, Slots int) (vec []float64) {
+	vec = make([]float64, Slots)
+	if err := ecd.Decode(dec.DecryptNew(ctVec), vec); err != nil {
+		panic(err)
+	}
+	return vec[:n]
+}
+
+// DecryptMatrix decrypts a row-major n x n matrix.
+func DecryptMatrix(ctRowMat *rlwe.Ciphertext, n int, dec *rlwe.Decryptor, ecd *ckks.Encoder, Slots int) (mat [][]float64) {
+	ptRowMat := dec.DecryptNew(ctRowMat)
+	rowMatVec := make([]float64, Slots)
+	if err := ecd.Decode(ptRowMat, rowMatVec); err != nil {
+		panic(err)
+	}
+
+	mat = make([][]float64, n)
+	for i := 0; i < n; i++ {
+		mat[i] = make([]float64, n)
+	}
+
+	nPow := math.Pow(float64(n), 2)
+	for i := 0; i < int(nPow); i++ {
+		row := i / n
+		col := i % n
+		mat[row][col] = rowMatVec[i]
+	}
+	return mat
+}
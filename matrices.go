@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/tuneinsight/lattigo/v6/circuits/ckks/bootstrapping"
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+	"github.com/tuneinsight/lattigo/v6/schemes/ckks"
+	"os"
+	"src/eigen/tracer"
+	"strconv"
+)
+
+// MatrixStride returns the block width of a matrix of a batch, a power of 2 of at least n*n+n.
+func MatrixStride(n int) (stride int) {
+	stride = 1
+	for stride < n*n+n {
+		stride <<= 1
+	}
+	return stride
+}
+
+// ReadMatrix reads a matrix from a CSV file of float rows.
+func ReadMatrix(path string) (A [][]float64) {
+	file, err := os.Open(path)
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		panic(err)
+	}
+
+	for _, row := range records {
+		var floatRow []float64
+		for _, val := range row {
+			f, err := strconv.ParseFloat(val, 64)
+			if err != nil {
+				panic(err)
+			}
+			floatRow = append(floatRow, f)
+		}
+		A = append(A, floatRow)
+	}
+	return A
+}
+
+// EncodeMatrixBlocks packs mats[b] in row-major order at the start of block b.
+func EncodeMatrixBlocks(mats [][][]float64, Slots int, stride int) (packed []float64) {
+	packed = make([]float64, Slots)
+	for b, A := range mats {
+		for i, row := range A {
+			copy(packed[b*stride+i*len(A):], row)
+		}
+	}
+	return packed
+}
+
+// DecryptMatrixBlocks decrypts the blocks row-major n x n matrices of ctRowMats.
+func DecryptMatrixBlocks(ctRowMats *rlwe.Ciphertext, n int, blocks int, stride int, dec *rlwe.Decryptor,
+	ecd *ckks.Encoder, Slots int) (mats [][][]float64) {
+
+	rowMatsVec := make([]float64, Slots)
+	if err := ecd.Decode(dec.DecryptNew(ctRowMats), rowMatsVec); err != nil {
+		panic(err)
+	}
+
+	mats = make([][][]float64, blocks)
+	for b := range mats {
+		mats[b] = make([][]float64, n)
+		for i := 0; i < n; i++ {
+			mats[b][i] = append([]float64(nil), rowMatsVec[b*stride+i*n:b*stride+(i+1)*n]...)
+		}
+	}
+	return mats
+}
+
+// HomomoBatchSVD returns the lE dominant eigenpairs of every matrix of mats, one slot block each.
+func HomomoBatchSVD(mats [][][]float64, lE int, Slots int, max_iter int, batch int, d int,
+	a float64, b float64, f1 float64, f2 float64, params ckks.Parameters, ecd *ckks.Encoder,
+	enc *rlwe.Encryptor, dec *rlwe.Decryptor, eval tracer.Evaluator, btpEval *bootstrapping.Evaluator,
+	workers int, seed int64) (singularVecs [][][]float64, singularVals [][]float64) {
+
+	var err error
+	n := len(mats[0])
+	blocks := len(mats)
+	stride := MatrixStride(n)
+
+	pta := BlockPlaintext(a, 1, blocks, stride, Slots, params, ecd)
+	ptb := BlockPlaintext(b, 1, blocks, stride, Slots, params, ecd)
+	ptf1 := BlockPlaintext(f1, 1, blocks, stride, Slots, params, ecd)
+	ptf2 := BlockPlaintext(f2, 1, blocks, stride, Slots, params, ecd)
+
+	ptRowA := NewSlotsPlaintext(params, params.MaxLevel(), Slots)
+	if err = ecd.Encode(EncodeMatrixBlocks(mats, Slots, stride), ptRowA); err != nil {
+		panic(err)
+	}
+	ctRowA, err := enc.EncryptNew(ptRowA)
+	if err != nil {
+		panic(err)
+	}
+
+	// A single copy shifted by n inside each block is enough for the diagonal rotations
+	rep := NewReplicator(n, 2)
+
+	singularVecs = make([][][]float64, blocks)
+	singularVals = make([][]float64, blocks)
+	for k := range mats {
+		singularVecs[k] = make([][]float64, lE)
+		singularVals[k] = make([]float64, lE)
+	}
+
+	for i := 0; i < lE; i++ {
+		fmt.Println()
+		fmt.Printf("the %d-th iteration of the batch of %d matrices...", i+1, blocks)
+
+		vecs := StartVectors(seed, i, blocks, n)
+
+		ptVec := NewSlotsPlaintext(params, params.MaxLevel(), Slots)
+		if err = ecd.Encode(EncodeBlocks(vecs, Slots, stride), ptVec); err != nil {
+			panic(err)
+		}
+		ctVec, err := enc.EncryptNew(ptVec)
+		if err != nil {
+			panic(err)
+		}
+
+		lt, ltEval := LinearTransMatrices(mats, Slots, n, stride, ctVec, params, ecd, eval)
+		ctEigenVec, ctEigenVal, _ := HomomoPowerMethodMultiStart(lt, ltEval, ctVec, eval, max_iter, batch, n,
+			ptf1, ptf2, pta, ptb, btpEval, d, rep)
+
+		ctRowA, _ = HomomoEigenShiftBlocks(ctRowA, ctEigenVec, ctEigenVal, eval, n, batch, blocks, stride,
+			params, ecd, btpEval, workers, nil)
+		mats = DecryptMatrixBlocks(ctRowA, n, blocks, stride, dec, ecd, Slots)
+
+		eigenVecList := make([]float64, Slots)
+		if err = ecd.Decode(dec.DecryptNew(ctEigenVec), eigenVecList); err != nil {
+			panic(err)
+		}
+		eigenValList := make([]float64, Slots)
+		if err = ecd.Decode(dec.DecryptNew(ctEigenVal), eigenValList); err != nil {
+			panic(err)
+		}
+
+		for k := range mats {
+			singularVecs[k][i] = eigenVecList[k*stride : k*stride+n]
+			singularVals[k][i] = eigenValList[k*stride]
+			fmt.Printf("%2sMatrix %d SingularVal: ", "", k)
+			fmt.Println(singularVals[k][:i+1])
+		}
+	}
+
+	return singularVecs, singularVals
+}
+
+// WriteSVD writes one row per eigenpair, the eigenvector then the eigenvalue.
+func WriteSVD(path string, singularVec [][]float64, singularVal []float64) {
+	file, err := os.Create(path)
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	for i := range singularVal {
+		var row []string
+		for j := 0; j < len(singularVec[i]); j++ {
+			row = append(row, strconv.FormatFloat(singularVec[i][j], 'f', 20, 64))
+		}
+		row = append(row, strconv.FormatFloat(singularVal[i], 'f', 20, 64))
+		if err = writer.Write(row); err != nil {
+			panic(err)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"src/eigen/fixture"
+	"testing"
+)
+
+func TestHomomoBatchSVD(t *testing.T) {
+	if testing.Short() {
+		t.Skip("bootstraps about 60 times")
+	}
+	ctx := getBenchContext()
+
+	mats := [][][]float64{spectrumCases[0].A, spectrumCases[1].A}
+	n, lE := len(mats[0]), 2
+	Slots := ctx.Params.MaxSlots()
+	eval := ctx.evalWith(PipelineOptions{N: n, Matrices: len(mats)})
+
+	singularVecs, singularVals := HomomoBatchSVD(mats, lE, Slots, testIters, 1, fixture.D, fixture.A, fixture.B,
+		fixture.F1, fixture.F2, ctx.Params, ctx.Ecd, ctx.Enc, ctx.Dec, eval, ctx.BtpEval, 1, 0)
+
+	// Each block runs the power method of its own matrix from its own start vector
+	for k, A := range mats {
+		startVecs := make([][]float64, lE)
+		for i := range startVecs {
+			startVecs[i] = StartVectors(0, i, len(mats), n)[k]
+		}
+		refVecs, refVals := ReferenceSVD(A, startVecs, testIters, false, fixture.D, fixture.A, fixture.B, fixture.F1,
+			fixture.F2)
+		exactVals, _ := JacobiEigen(A)
+		for i := 0; i < lE; i++ {
+			t.Run(fmt.Sprintf("%s eigenpair %d", spectrumCases[k].name, i+1), func(t *testing.T) {
+				checkEigenpair(t, A, singularVecs[k][i], singularVals[k][i], refVecs[i], refVals[i], exactVals[i])
+			})
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"fmt"
+	"github.com/tuneinsight/lattigo/v6/circuits/ckks/bootstrapping"
+	"github.com/tuneinsight/lattigo/v6/circuits/ckks/comparison"
+	"github.com/tuneinsight/lattigo/v6/circuits/ckks/lintrans"
+	"github.com/tuneinsight/lattigo/v6/circuits/ckks/minimax"
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+	"github.com/tuneinsight/lattigo/v6/schemes/ckks"
+	"src/eigen/normalize"
+	"src/eigen/tracer"
+)
+
+// MultiStartStride returns the block width of a start vector, a power of 2 of at least 2n.
+func MultiStartStride(n int) (stride int) {
+	stride = 1
+	for stride < 2*n {
+		stride <<= 1
+	}
+	return stride
+}
+
+func LinearTransBlocks(A [][]float64, Slots int, n int, blocks int, stride int, ctVec *rlwe.Ciphertext,
+	params ckks.Parameters, ecd *ckks.Encoder, eval tracer.Evaluator) (lt lintrans.LinearTransformation, ltEval *lintrans.Evaluator) {
+
+	// Every block gets its own copy of the diagonals
+	mats := make([][][]float64, blocks)
+	for b := range mats {
+		mats[b] = A
+	}
+	return LinearTransMatrices(mats, Slots, n, stride, ctVec, params, ecd, eval)
+}
+
+// LinearTransMatrices returns the product of block b by the n x n matrix mats[b].
+func LinearTransMatrices(mats [][][]float64, Slots int, n int, stride int, ctVec *rlwe.Ciphertext,
+	params ckks.Parameters, ecd *ckks.Encoder, eval tracer.Evaluator) (lt lintrans.LinearTransformation, ltEval *lintrans.Evaluator) {
+
+	diagonals := make(lintrans.Diagonals[float64])
+	for k := 0; k < n; k++ {
+		tmp := make([]float64, Slots)
+		for b, A := range mats {
+			for i := 0; i < n; i++ {
+				tmp[b*stride+i] = A[i][(i+k)%n]
+			}
+		}
+		diagonals[k] = tmp
+	}
+
+	ltparams := linearTransParams(params, n, ctVec.Level(), ctVec.LogDimensions)
+	lt = lintrans.NewTransformation(params, ltparams)
+	if err := lintrans.Encode(ecd, diagonals, lt); err != nil {
+		panic(err)
+	}
+
+	if err := CheckGaloisKeys(eval, lintrans.GaloisElements(params, ltparams)); err != nil {
+		panic(err)
+	}
+
+	ltEval = lintrans.NewEvaluator(eval)
+	return lt, ltEval
+}
+
+// EncodeBlocks packs vecs[b] at the start of block b.
+func EncodeBlocks(vecs [][]float64, Slots int, stride int) (packed []float64) {
+	packed = make([]float64, Slots)
+	for b, vec := range vecs {
+		copy(packed[b*stride:], vec)
+	}
+	return packed
+}
+
+// BlockPlaintext encodes value in the first vecLen slots of every block and zero elsewhere.
+func BlockPlaintext(value float64, vecLen int, blocks int, stride int, Slots int,
+	params ckks.Parameters, ecd *ckks.Encoder) (pt *rlwe.Plaintext) {
+
+	vec := make([]float64, Slots)
+	for b := 0; b < blocks; b++ {
+		for j := 0; j < vecLen; j++ {
+			vec[b*stride+j] = value
+		}
+	}
+
+	pt = NewSlotsPlaintext(params, params.MaxLevel(), Slots)
+	if err := ecd.Encode(vec, pt); err != nil {
+		panic(err)
+	}
+	return pt
+}
+
+// NewComparisonEvaluator returns the evaluator of the encrypted step function.
+func NewComparisonEvaluator(params ckks.Parameters, eval tracer.Evaluator,
+	btpEval *bootstrapping.Evaluator) (cmpEval *comparison.Evaluator) {
+
+	if err := CheckGaloisKeys(eval, []uint64{params.GaloisElementForComplexConjugation()}); err != nil {
+		panic(err)
+	}
+
+	return comparison.NewEvaluator(params, minimax.NewEvaluator(params, tracer.Unwrap(eval), SparseBootstrapper{btpEval}))
+}
+
+func HomomoPowerMethodMultiStart(lt lintrans.LinearTransformation,
+	ltEval *lintrans.Evaluator, ctVec *rlwe.Ciphertext, eval tracer.Evaluator, max_iter int, batch int,
+	n int, ptf1 *rlwe.Plaintext, ptf2 *rlwe.Plaintext, pta *rlwe.Plaintext, ptb *rlwe.Plaintext,
+	btpEval *bootstrapping.Evaluator, d int, rep *Replicator) (ctNormVec *rlwe.Ciphertext, ctEigenVal *rlwe.Ciphertext,
+	ctResidual *rlwe.Ciphertext) {
+
+	// pta, ptb, ptf1 and ptf2 must hold their constant at the start of every
+	// block, so that every start vector is normalized by its own norm.
+	fmt.Println()
+	fmt.Println("3. Performing homomorphic multi-start power method...")
+
+	ctNormVec = ctVec
+	var ctPrevVec, ctLintransVec, ctVecMulSum *rlwe.Ciphertext
+	for i := 0; i < max_iter; i++ {
+		fmt.Println()
+		fmt.Printf("%2sthe %d-th iteration...", "", i+1)
+		fmt.Println()
+		ctNormVec = EnsureLevel(ctNormVec, StagePowerStep, btpEval)
+		ctPrevVec = ctNormVec
+		ctLintransVec = HomomoMatMutiVec(lt, ltEval, ctNormVec, eval, rep)
+
+		ctVecMulSum = normalize.MulSumVec(eval, ctLintransVec, ctLintransVec, eval, batch, n)
+		cty0 := normalize.LinearApprox(ctVecMulSum, eval, pta, ptb)
+		ctNormVal := normalize.HomomoNewton(ptf1, ptf2, ctVecMulSum, cty0, eval, btpEval, d)
+
+		ctNormVec = normalize.NormVect(ctNormVal, ctLintransVec, eval, eval, n)
+	}
+
+	// Per-block residual ||Av - theta v||^2 = ||Av||^2 - <Av,v>^2 of the unit v of the last product
+	ctTheta := normalize.MulSumVec(eval, ctLintransVec, ctPrevVec, eval, batch, n)
+	ctTheta2, err := eval.MulRelinNew(ctTheta, ctTheta)
+	if err != nil {
+		panic(err)
+	}
+	if err = eval.Rescale(ctTheta2, ctTheta2); err != nil {
+		panic(err)
+	}
+	if ctResidual, err = eval.SubNew(ctVecMulSum, ctTheta2); err != nil {
+		panic(err)
+	}
+
+	// Per-block eigenvalue <Av,v>/<v,v>
+	ctNormVec = EnsureLevel(ctNormVec, StageEigenVal, btpEval)
+	ctEigenVal = HomomoEigenVal(ctLintransVec, ctNormVec, eval, batch, n, ptf1, ptf2, pta, ptb, btpEval, d)
+
+	fmt.Println()
+
+	return ctNormVec, ctEigenVal, ctResidual
+}
+
+// HomomoSelectBest keeps in block 0 the start vector of smallest residual.
+func HomomoSelectBest(ctVec *rlwe.Ciphertext, ctEigenVal *rlwe.Ciphertext, ctResidual *rlwe.Ciphertext,
+	cmpEval *comparison.Evaluator, eval tracer.Evaluator, btpEval *bootstrapping.Evaluator, n int, starts int,
+	stride int, cmpScale float64, Slots int, params ckks.Parameters, ecd *ckks.Encoder) (ctBestVec *rlwe.Ciphertext, ctBestVal *rlwe.Ciphertext) {
+
+	fmt.Println()
+	fmt.Printf("%4s3.3. Performing homomorphic selection of the best start vector...", "")
+
+	var err error
+	if err = CheckGaloisKeys(eval, SelectBestGaloisElements(params, starts, stride)); err != nil {
+		panic(err)
+	}
+
+	// Broadcast each eigenvalue and residual over the n slots of its block so
+	// that the step computed from them directly weights the vector slots.
+	ctValBlock := ctEigenVal.CopyNew()
+	if err = eval.Replicate(ctValBlock, 1, n, ctValBlock); err != nil {
+		panic(err)
+	}
+	ctResBlock := ctResidual.CopyNew()
+	if err = eval.Replicate(ctResBlock, 1, n, ctResBlock); err != nil {
+		panic(err)
+	}
+
+	// Slightly favours the lower block so that two candidates with the same
+	// residual, which may be opposite eigenvectors, are never averaged.
+	ptTie := BlockPlaintext(1.0/(1<<20), n, starts, stride, Slots, params, ecd)
+
+	ctBestVec = ctVec
+	ctBestVal = ctValBlock
+	ctBestRes := ctResBlock
+	for k := 1; k < starts; k <<= 1 {
+		rotLeft := k * stride
+
+		ctOtherVec, err := eval.RotateNew(ctBestVec, rotLeft)
+		if err != nil {
+			panic(err)
+		}
+		ctOtherVal, err := eval.RotateNew(ctBestVal, rotLeft)
+		if err != nil {
+			panic(err)
+		}
+		ctOtherRes, err := eval.RotateNew(ctBestRes, rotLeft)
+		if err != nil {
+			panic(err)
+		}
+
+		ctDiff, err := eval.SubNew(ctOtherRes, ctBestRes)
+		if err != nil {
+			panic(err)
+		}
+		if err = eval.Mul(ctDiff, cmpScale, ctDiff); err != nil {
+			panic(err)
+		}
+		if err = eval.Rescale(ctDiff, ctDiff); err != nil {
+			panic(err)
+		}
+		if err = eval.Add(ctDiff, ptTie, ctDiff); err != nil {
+			panic(err)
+		}
+
+		ctStep, err := cmpEval.Step(ctDiff)
+		if err != nil {
+			panic(err)
+		}
+
+		// best = step * (best - other) + other
+		ctBestVec = selectByStep(ctStep, ctBestVec, ctOtherVec, eval)
+		ctBestVal = selectByStep(ctStep, ctBestVal, ctOtherVal, eval)
+		// The residuals are not compared after the last round
+		if k<<1 < starts {
+			ctBestRes = selectByStep(ctStep, ctBestRes, ctOtherRes, eval)
+		}
+	}
+
+	// Keep only the winner in block 0
+	ptVecMask := BlockPlaintext(1, n, 1, stride, Slots, params, ecd)
+	ptValMask := BlockPlaintext(1, 1, 1, stride, Slots, params, ecd)
+
+	ctBestVec, err = eval.MulRelinNew(ctBestVec, ptVecMask)
+	if err != nil {
+		panic(err)
+	}
+	if err = eval.Rescale(ctBestVec, ctBestVec); err != nil {
+		panic(err)
+	}
+
+	ctBestVal, err = eval.MulRelinNew(ctBestVal, ptValMask)
+	if err != nil {
+		panic(err)
+	}
+	if err = eval.Rescale(ctBestVal, ctBestVal); err != nil {
+		panic(err)
+	}
+
+	// The sign polynomial leaves too few levels for the deflation
+	if ctBestVec, err = btpEval.Evaluate(ctBestVec); err != nil {
+		panic(err)
+	}
+	if ctBestVal, err = btpEval.Evaluate(ctBestVal); err != nil {
+		panic(err)
+	}
+
+	fmt.Println()
+
+	return ctBestVec, ctBestVal
+}
+
+func selectByStep(ctStep *rlwe.Ciphertext, ct0 *rlwe.Ciphertext, ct1 *rlwe.Ciphertext,
+	eval tracer.Evaluator) (ctSel *rlwe.Ciphertext) {
+
+	ctDiff, err := eval.SubNew(ct0, ct1)
+	if err != nil {
+		panic(err)
+	}
+	ctSel, err = eval.MulRelinNew(ctDiff, ctStep)
+	if err != nil {
+		panic(err)
+	}
+	if err = eval.Rescale(ctSel, ctSel); err != nil {
+		panic(err)
+	}
+	if err = eval.Add(ctSel, ct1, ctSel); err != nil {
+		panic(err)
+	}
+	return ctSel
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+	"math"
+	"src/eigen/fixture"
+	"testing"
+)
+
+func TestHomomoSelectBest(t *testing.T) {
+	if testing.Short() {
+		t.Skip("bootstraps about 40 times per case")
+	}
+	ctx := getBenchContext()
+	n, starts := 4, 2
+	Slots := ctx.Params.MaxSlots()
+	stride := MultiStartStride(n)
+	eval := ctx.evalWith(PipelineOptions{N: n, Starts: starts})
+	blockPlaintext := func(value float64) *rlwe.Plaintext {
+		return BlockPlaintext(value, 1, starts, stride, Slots, ctx.Params, ctx.Ecd)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		A      [][]float64
+		vecs   [][]float64
+		best   int
+		lambda float64
+	}{
+		{
+			// Block 0 is still far from the dominant eigenvector, block 1 at 0.01
+			name:   "largest estimate",
+			A:      diagonalMatrix(9, 3, 1, 0.5),
+			vecs:   [][]float64{{0.1, 0.9, 0.3, 0.3}, {0.6, -0.3, -0.8, 0.4}},
+			best:   1,
+			lambda: 9,
+		},
+		{
+			// Block 1 estimates 8.7 between 9 and 8 at a residual of 0.2, block 0
+			// has converged to the eigenvalue 2 at a residual of 0.003
+			name:   "smaller estimate",
+			A:      diagonalMatrix(9, 8, 2, 1),
+			vecs:   [][]float64{{0, 0, 0.9, 0.4}, {0.7, 0.7, 0.1, 0.1}},
+			best:   0,
+			lambda: 2,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			A := tc.A
+			ctVec := ctx.Encrypt(EncodeBlocks(tc.vecs, Slots, stride), ctx.Params.MaxLevel())
+			lt, ltEval := LinearTransBlocks(A, Slots, n, starts, stride, ctVec, ctx.Params, ctx.Ecd, eval)
+			ctMultiVec, ctMultiVal, ctMultiRes := HomomoPowerMethodMultiStart(lt, ltEval, ctVec, eval, testIters, 1, n,
+				blockPlaintext(fixture.F1), blockPlaintext(fixture.F2), blockPlaintext(fixture.A), blockPlaintext(fixture.B),
+				ctx.BtpEval, fixture.D, NewReplicator(n, 2))
+
+			// Every block holds the power method of its own start vector and the
+			// residual of the iterate before the last one
+			multiVec, multiVal, multiRes := ctx.Decrypt(ctMultiVec, Slots), ctx.Decrypt(ctMultiVal, Slots),
+				ctx.Decrypt(ctMultiRes, Slots)
+			for s, vec := range tc.vecs {
+				refVec, refVal := ReferencePowerMethod(A, vec, testIters, false, fixture.D, fixture.A, fixture.B,
+					fixture.F1, fixture.F2)
+				if a := angle(multiVec[s*stride:s*stride+n], refVec); a > 0.5 {
+					t.Errorf("block %d: eigenvector at %.3f degrees of the plaintext power method", s, a)
+				}
+				if err := math.Abs(multiVal[s*stride]-refVal) / refVal; err > 1e-3 {
+					t.Errorf("block %d: eigenvalue %v, plaintext power method %v", s, multiVal[s*stride], refVal)
+				}
+
+				prevVec, _ := ReferencePowerMethod(A, vec, testIters-1, false, fixture.D, fixture.A, fixture.B,
+					fixture.F1, fixture.F2)
+				r := matVec(A, prevVec)
+				theta := dot(r, prevVec)
+				if want := dot(r, r) - theta*theta; math.Abs(multiRes[s*stride]-want) > 1e-3*math.Max(want, 1) {
+					t.Errorf("block %d: residual %v, plaintext %v", s, multiRes[s*stride], want)
+				}
+			}
+
+			cmpScale := 1 / (2 * frobenius2(A))
+			ctBestVec, ctBestVal := HomomoSelectBest(ctMultiVec, ctMultiVal, ctMultiRes,
+				NewComparisonEvaluator(ctx.Params, eval, ctx.BtpEval), eval, ctx.BtpEval, n, starts, stride, cmpScale,
+				Slots, ctx.Params, ctx.Ecd)
+
+			refVec, refVal := ReferencePowerMethod(A, tc.vecs[tc.best], testIters, false, fixture.D, fixture.A,
+				fixture.B, fixture.F1, fixture.F2)
+			checkEigenpair(t, A, ctx.Decrypt(ctBestVec, n), ctx.Decrypt(ctBestVal, 1)[0], refVec, refVal, tc.lambda)
+
+			// Only block 0 is kept
+			bestVec := ctx.Decrypt(ctBestVec, Slots)
+			for j := n; j < starts*stride; j++ {
+				if math.Abs(bestVec[j]) > 1e-3 {
+					t.Fatalf("slot %d outside block 0 holds %v", j, bestVec[j])
+				}
+			}
+		})
+	}
+}
@@ -4,10 +4,10 @@ import (
 	"fmt"
 	"github.com/tuneinsight/lattigo/v6/circuits/ckks/bootstrapping"
 	"github.com/tuneinsight/lattigo/v6/core/rlwe"
-	"github.com/tuneinsight/lattigo/v6/schemes/ckks"
+	"src/eigen/tracer"
 )
 
-func LinearApprox(ctx0 *rlwe.Ciphertext, eval *ckks.Evaluator,
+func LinearApprox(ctx0 *rlwe.Ciphertext, eval tracer.Evaluator,
 	pta *rlwe.Plaintext, ptb *rlwe.Plaintext) (cty0 *rlwe.Ciphertext) {
 
 	var err error
@@ -24,7 +24,15 @@ func LinearApprox(ctx0 *rlwe.Ciphertext, eval *ckks.Evaluator,
 
 func HomomoNewton(ptf1 *rlwe.Plaintext, ptf2 *rlwe.Plaintext,
 	ctVecMulSum *rlwe.Ciphertext, cty0 *rlwe.Ciphertext,
-	eval *ckks.Evaluator, btpEval *bootstrapping.Evaluator, d int) (ctyd *rlwe.Ciphertext) {
+	eval tracer.Evaluator, btpEval *bootstrapping.Evaluator, d int) (ctyd *rlwe.Ciphertext) {
+	return HomomoNewtonSteps(ptf1, ptf2, ctVecMulSum, cty0, eval, btpEval, d, nil)
+}
+
+// HomomoNewtonSteps is HomomoNewton calling step, unless nil, after every Newton step.
+func HomomoNewtonSteps(ptf1 *rlwe.Plaintext, ptf2 *rlwe.Plaintext,
+	ctVecMulSum *rlwe.Ciphertext, cty0 *rlwe.Ciphertext,
+	eval tracer.Evaluator, btpEval *bootstrapping.Evaluator, d int,
+	step func(i int, cty *rlwe.Ciphertext)) (ctyd *rlwe.Ciphertext) {
 
 	fmt.Println()
 	fmt.Printf("%4s3.1. Performing homomorphic newton method...", "")
@@ -73,14 +81,17 @@ func HomomoNewton(ptf1 *rlwe.Plaintext, ptf2 *rlwe.Plaintext,
 
 		cty0, err = eval.SubNew(t, s)
 
-		cty0, err = btpEval.Bootstrap(cty0)
+		// Evaluate keeps the sparse slots of cty0, Bootstrap would repack them
+		cty0, err = btpEval.Evaluate(cty0)
 		if err != nil {
 			panic(err)
 		}
 
+		if step != nil {
+			step(i, cty0)
+		}
 	}
 	ctyd = cty0
 
-	//fmt.Printf("Newton method%s", ckks.GetPrecisionStats(params, ecd, dec, want, valyd, 0, false).String())
 	return ctyd
 }
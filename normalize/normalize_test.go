@@ -0,0 +1,126 @@
+package normalize
+
+import (
+	"fmt"
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+	"github.com/tuneinsight/lattigo/v6/schemes/ckks"
+	"math"
+	"src/eigen/fixture"
+	"sync"
+	"testing"
+)
+
+// The tests run under the fixture preset, with the inner sum and replication
+// keys of vectors up to testMaxLen slots.
+const testMaxLen = 8
+
+type testContext struct {
+	*fixture.Context
+	eval *ckks.Evaluator
+}
+
+var (
+	testOnce sync.Once
+	testCtx  *testContext
+)
+
+func getTestContext() *testContext {
+	testOnce.Do(func() {
+		ctx := fixture.Get()
+		var galEls []uint64
+		for n := 1; n <= testMaxLen; n++ {
+			galEls = append(galEls, ctx.Params.GaloisElementsForInnerSum(1, n)...)
+			galEls = append(galEls, ctx.Params.GaloisElementsForReplicate(1, n)...)
+		}
+		testCtx = &testContext{Context: ctx, eval: ctx.NewEvaluator(galEls)}
+	})
+	return testCtx
+}
+
+// encrypt encrypts values at the maximum level.
+func (ctx *testContext) encrypt(values []float64) *rlwe.Ciphertext {
+	return ctx.Encrypt(values, ctx.Params.MaxLevel())
+}
+
+func TestMulSumVec(t *testing.T) {
+	ctx := getTestContext()
+	for _, tc := range []struct {
+		vec1, vec2 []float64
+	}{
+		{[]float64{1, 1, 1, 1}, []float64{1, 1, 1, 1}},
+		{[]float64{1, 2, 3, 4}, []float64{1, 2, 3, 4}},
+		{[]float64{1, -2, 3, -4, 5}, []float64{0.5, 0.5, -1, 2, 0}},
+		{[]float64{3, 1, 4, 1, 5, 9, 2, 6}, []float64{2, 7, 1, 8, 2, 8, 1, 8}},
+	} {
+		t.Run(fmt.Sprintf("n=%d", len(tc.vec1)), func(t *testing.T) {
+			want := 0.0
+			for i := range tc.vec1 {
+				want += tc.vec1[i] * tc.vec2[i]
+			}
+			ct := MulSumVec(ctx.eval, ctx.encrypt(tc.vec1), ctx.encrypt(tc.vec2), ctx.eval, 1, len(tc.vec1))
+			if have := ctx.Decrypt(ct, 1)[0]; math.Abs(have-want) > 1e-4 {
+				t.Errorf("have %v, want %v", have, want)
+			}
+		})
+	}
+}
+
+func TestLinearApprox(t *testing.T) {
+	ctx := getTestContext()
+	for _, x := range []float64{1, 30, 250} {
+		t.Run(fmt.Sprintf("x=%v", x), func(t *testing.T) {
+			ct := LinearApprox(ctx.encrypt([]float64{x}), ctx.eval, ctx.Encode(fixture.A), ctx.Encode(fixture.B))
+			if have, want := ctx.Decrypt(ct, 1)[0], fixture.A*x+fixture.B; math.Abs(have-want) > 1e-6 {
+				t.Errorf("have %v, want %v", have, want)
+			}
+		})
+	}
+}
+
+func TestHomomoNewton(t *testing.T) {
+	if testing.Short() {
+		t.Skip("bootstraps d times per case")
+	}
+	ctx := getTestContext()
+	// Inputs within the range of the initial guess of LinearApprox
+	for _, x := range []float64{4, 16, 64, 100} {
+		t.Run(fmt.Sprintf("x=%v", x), func(t *testing.T) {
+			ctx0 := ctx.encrypt([]float64{x})
+			cty0 := LinearApprox(ctx0, ctx.eval, ctx.Encode(fixture.A), ctx.Encode(fixture.B))
+
+			steps := 0
+			ctyd := HomomoNewtonSteps(ctx.Encode(fixture.F1), ctx.Encode(fixture.F2), ctx0, cty0, ctx.eval, ctx.BtpEval,
+				fixture.D, func(i int, cty *rlwe.Ciphertext) { steps++ })
+			if steps != fixture.D {
+				t.Errorf("%d steps, want %d", steps, fixture.D)
+			}
+
+			have, want := ctx.Decrypt(ctyd, 1)[0], 1/math.Sqrt(x)
+			if err := math.Abs(have-want) / want; err > 1e-3 {
+				t.Errorf("have %v, want %v: relative error %.2e", have, want, err)
+			}
+		})
+	}
+}
+
+func TestNormVect(t *testing.T) {
+	ctx := getTestContext()
+	for _, tc := range []struct {
+		norm float64
+		vec  []float64
+	}{
+		{0.5, []float64{1, 2, 3, 4}},
+		{0.1, []float64{10, -20, 30, -40, 50}},
+		{1 / math.Sqrt(204), []float64{3, 1, 4, 1, 5, 9, 2, 6}},
+	} {
+		t.Run(fmt.Sprintf("n=%d", len(tc.vec)), func(t *testing.T) {
+			ct := NormVect(ctx.encrypt([]float64{tc.norm}), ctx.encrypt(tc.vec), ctx.eval, ctx.eval, len(tc.vec))
+			have := ctx.Decrypt(ct, len(tc.vec))
+			for i := range tc.vec {
+				if want := tc.norm * tc.vec[i]; math.Abs(have[i]-want) > 1e-4 {
+					t.Fatalf("slot %d: have %v, want %v", i, have[i], want)
+				}
+			}
+		})
+	}
+}
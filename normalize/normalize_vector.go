@@ -3,11 +3,11 @@ package normalize
 import (
 	"fmt"
 	"github.com/tuneinsight/lattigo/v6/core/rlwe"
-	"github.com/tuneinsight/lattigo/v6/schemes/ckks"
+	"src/eigen/tracer"
 )
 
-func MulSumVec(evalInnsum *ckks.Evaluator, ctVec1 *rlwe.Ciphertext, ctVec2 *rlwe.Ciphertext,
-	eval *ckks.Evaluator, batch int, n int) (ctVecMulSum *rlwe.Ciphertext) {
+func MulSumVec(evalInnsum tracer.Evaluator, ctVec1 *rlwe.Ciphertext, ctVec2 *rlwe.Ciphertext,
+	eval tracer.Evaluator, batch int, n int) (ctVecMulSum *rlwe.Ciphertext) {
 
 	var err error
 
@@ -27,36 +27,28 @@ func MulSumVec(evalInnsum *ckks.Evaluator, ctVec1 *rlwe.Ciphertext, ctVec2 *rlwe
 }
 
 func NormVect(ctNormVal *rlwe.Ciphertext, ctVec *rlwe.Ciphertext,
-	ctVec0 *rlwe.Ciphertext, rotEval *ckks.Evaluator,
-	eval *ckks.Evaluator, vecLen int, rot int) (ctNormVec *rlwe.Ciphertext) {
+	evalRep tracer.Evaluator, eval tracer.Evaluator, vecLen int) (ctNormVec *rlwe.Ciphertext) {
 
 	fmt.Println()
 	fmt.Printf("%4s3.2. Performing homomorphic normalize vector...", "")
 
 	// Normalize Vector
 
-	
-	// multi & add & rotate
-	for i := 0; i < vecLen; i++ {
-		tempVec, err := eval.MulRelinNew(ctVec, ctNormVal)
-		if err != nil {
-			panic(err)
-		}
-		if err = eval.Rescale(tempVec, tempVec); err != nil {
-			panic(err)
-		}
-
-		ctVec0, err = eval.AddNew(ctVec0, tempVec)
-		if err != nil {
-			panic(err)
-		}
-
-		ctNormVal, err = rotEval.RotateNew(ctNormVal, rot)
-		if err != nil {
-			panic(err)
-		}
+	// replicate slot 0 over the vecLen slots in log2(vecLen) rotate & add,
+	// evalRep must hold the keys of GaloisElementsForReplicate(1, vecLen)
+	ctNormVals := ctNormVal.CopyNew()
+	if err := evalRep.Replicate(ctNormVals, 1, vecLen, ctNormVals); err != nil {
+		panic(err)
+	}
+
+	// multi
+	ctNormVec, err := eval.MulRelinNew(ctVec, ctNormVals)
+	if err != nil {
+		panic(err)
+	}
+	if err = eval.Rescale(ctNormVec, ctNormVec); err != nil {
+		panic(err)
 	}
-	ctNormVec = ctVec0
 
 	return ctNormVec
 }
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+	"github.com/tuneinsight/lattigo/v6/schemes/ckks"
+	"math/bits"
+	"os"
+	"src/eigen/tracer"
+	"strconv"
+)
+
+// PCALogSlots returns the log2 of the slots holding the m x p data.
+func PCALogSlots(params ckks.Parameters, m int, p int) (LogSlots int) {
+	LogSlots = bits.Len(uint(m*p - 1))
+	if LogSlots > params.LogMaxSlots() {
+		panic(fmt.Errorf("%d x %d data do not fit in %d slots", m, p, params.MaxSlots()))
+	}
+	return LogSlots
+}
+
+// PCAGaloisElements returns the Galois elements of the PCA of m x p data.
+func PCAGaloisElements(params ckks.Parameters, m int, p int) (galEls []uint64) {
+	galEls = append(galEls, params.GaloisElementsForInnerSum(p, m)...)
+	galEls = append(galEls, params.GaloisElementsForReplicate(p, m)...)
+	galEls = append(galEls, params.GaloisElementsForInnerSum(1, m*p)...)
+	galEls = append(galEls, params.GaloisElementsForReplicate(1, p)...)
+	for j := 1; j < p; j++ {
+		galEls = append(galEls, params.GaloisElement(j), params.GaloisElement(-j*p))
+	}
+	return galEls
+}
+
+// HomomoCenter returns the m x p row-major data minus its column means.
+func HomomoCenter(ctX *rlwe.Ciphertext, eval tracer.Evaluator, m int, p int, params ckks.Parameters,
+	ecd *ckks.Encoder) (ctCentered *rlwe.Ciphertext) {
+
+	fmt.Println()
+	fmt.Printf("%4sPerforming homomorphic mean-centering...", "")
+
+	var err error
+	if err = CheckGaloisKeys(eval, PCAGaloisElements(params, m, p)); err != nil {
+		panic(err)
+	}
+
+	ctMean := ctX.CopyNew()
+	if err = eval.InnerSum(ctMean, p, m, ctMean); err != nil {
+		panic(err)
+	}
+	meanMask := make([]float64, p)
+	for j := range meanMask {
+		meanMask[j] = 1 / float64(m)
+	}
+	ctMean = mulPlainRescale(ctMean, meanMask, params, eval, ecd)
+	if err = eval.Replicate(ctMean, p, m, ctMean); err != nil {
+		panic(err)
+	}
+
+	if ctCentered, err = eval.SubNew(ctX, ctMean); err != nil {
+		panic(err)
+	}
+
+	fmt.Println()
+
+	return ctCentered
+}
+
+// HomomoCovariance returns the row-major p x p covariance Xc^T Xc / m of the centered m x p data.
+func HomomoCovariance(ctCentered *rlwe.Ciphertext, eval tracer.Evaluator, m int, p int,
+	params ckks.Parameters, ecd *ckks.Encoder, workers int) (ctCov *rlwe.Ciphertext) {
+
+	fmt.Println()
+	fmt.Printf("%4sPerforming homomorphic covariance...", "")
+
+	if err := CheckGaloisKeys(eval, PCAGaloisElements(params, m, p)); err != nil {
+		panic(err)
+	}
+
+	ctRows := ParallelEval(workers, p, eval, ecd, func(j int, eval tracer.Evaluator, ecd *ckks.Encoder) *rlwe.Ciphertext {
+		colMask := make([]float64, m*p)
+		for i := 0; i < m; i++ {
+			colMask[i*p+j] = 1.0
+		}
+		ctCol := mulPlainRescale(ctCentered, colMask, params, eval, ecd)
+
+		// Bring column j to the start of every row and broadcast it over the row
+		var err error
+		if j > 0 {
+			if ctCol, err = eval.RotateNew(ctCol, j); err != nil {
+				panic(err)
+			}
+		}
+		if err = eval.Replicate(ctCol, 1, p, ctCol); err != nil {
+			panic(err)
+		}
+
+		// x[j] * x summed over the rows
+		ctRow, err := eval.MulRelinNew(ctCol, ctCentered)
+		if err != nil {
+			panic(err)
+		}
+		if err = eval.Rescale(ctRow, ctRow); err != nil {
+			panic(err)
+		}
+		if err = eval.InnerSum(ctRow, p, m, ctRow); err != nil {
+			panic(err)
+		}
+
+		rowMask := make([]float64, p)
+		for k := range rowMask {
+			rowMask[k] = 1 / float64(m)
+		}
+		ctRow = mulPlainRescale(ctRow, rowMask, params, eval, ecd)
+
+		// Bring row j to slots [j*p, (j+1)*p)
+		if j > 0 {
+			if ctRow, err = eval.RotateNew(ctRow, -j*p); err != nil {
+				panic(err)
+			}
+		}
+		return ctRow
+	})
+	ctCov = SumInOrder(ctRows, eval)
+
+	fmt.Println()
+
+	return ctCov
+}
+
+// HomomoTotalVariance returns tr(C) = ||Xc||_F^2 / m in slot 0.
+func HomomoTotalVariance(ctCentered *rlwe.Ciphertext, eval tracer.Evaluator, m int, p int,
+	params ckks.Parameters, ecd *ckks.Encoder) (ctTotalVar *rlwe.Ciphertext) {
+
+	ctSquares, err := eval.MulRelinNew(ctCentered, ctCentered)
+	if err != nil {
+		panic(err)
+	}
+	if err = eval.Rescale(ctSquares, ctSquares); err != nil {
+		panic(err)
+	}
+	if err = eval.InnerSum(ctSquares, 1, m*p, ctSquares); err != nil {
+		panic(err)
+	}
+	return mulPlainRescale(ctSquares, []float64{1 / float64(m)}, params, eval, ecd)
+}
+
+// ColumnMeans returns the column means of the data X.
+func ColumnMeans(X [][]float64) (means []float64) {
+	means = make([]float64, len(X[0]))
+	for _, row := range X {
+		for j := range row {
+			means[j] += row[j] / float64(len(X))
+		}
+	}
+	return means
+}
+
+// Covariance returns the covariance Xc^T Xc / m of the m x p data X in float64.
+func Covariance(X [][]float64) (C [][]float64) {
+	m, p := len(X), len(X[0])
+	means := ColumnMeans(X)
+
+	C = make([][]float64, p)
+	for j := range C {
+		C[j] = make([]float64, p)
+	}
+	for _, row := range X {
+		for j := 0; j < p; j++ {
+			for k := 0; k < p; k++ {
+				C[j][k] += (row[j] - means[j]) * (row[k] - means[k]) / float64(m)
+			}
+		}
+	}
+	return C
+}
+
+// ExplainedVariance returns the share of the total variance of each eigenvalue and their running sum.
+func ExplainedVariance(eigenVals []float64, totalVar float64) (ratios []float64, cumulative []float64) {
+	sum := 0.0
+	for _, val := range eigenVals {
+		ratios = append(ratios, val/totalVar)
+		sum += val / totalVar
+		cumulative = append(cumulative, sum)
+	}
+	return ratios, cumulative
+}
+
+// PrintExplainedVariance prints the explained variance of each principal component.
+func PrintExplainedVariance(eigenVals []float64, totalVar float64) {
+	ratios, cumulative := ExplainedVariance(eigenVals, totalVar)
+	fmt.Printf("%2stotal variance: %v\n", "", totalVar)
+	fmt.Printf("%2s%-3s %14s %11s %11s\n", "", "#", "eigenvalue", "explained", "cumulative")
+	for i := range eigenVals {
+		fmt.Printf("%2s%-3d %14.8f %10.4f%% %10.4f%%\n", "", i+1, eigenVals[i], 100*ratios[i], 100*cumulative[i])
+	}
+}
+
+// WriteExplainedVariance writes the eigenvalue and the explained variances of each principal component.
+func WriteExplainedVariance(path string, eigenVals []float64, totalVar float64) {
+	file, err := os.Create(path)
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	ratios, cumulative := ExplainedVariance(eigenVals, totalVar)
+	for i := range eigenVals {
+		row := []string{
+			strconv.FormatFloat(eigenVals[i], 'f', 20, 64),
+			strconv.FormatFloat(ratios[i], 'f', 20, 64),
+			strconv.FormatFloat(cumulative[i], 'f', 20, 64),
+		}
+		if err = writer.Write(row); err != nil {
+			panic(err)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestHomomoCovariance(t *testing.T) {
+	ctx := getBenchContext()
+
+	// 12 x 5 data: neither the rows nor the columns fill a power of two
+	m, p := 12, 5
+	r := rand.New(rand.NewSource(1))
+	X := make([][]float64, m)
+	for i := range X {
+		X[i] = make([]float64, p)
+		for j := range X[i] {
+			X[i][j] = float64(j) + 2*r.Float64() - 1
+		}
+	}
+
+	eval := ctx.evalWith(PipelineOptions{N: p, PCARows: m})
+	ctCentered := HomomoCenter(ctx.encryptMatrix(X), eval, m, p, ctx.Params, ctx.Ecd)
+
+	means := ColumnMeans(X)
+	centered := ctx.Decrypt(ctCentered, m*p)
+	for i := 0; i < m; i++ {
+		for j := 0; j < p; j++ {
+			if want := X[i][j] - means[j]; math.Abs(centered[i*p+j]-want) > 1e-4 {
+				t.Fatalf("centered entry (%d, %d): have %v, want %v", i, j, centered[i*p+j], want)
+			}
+		}
+	}
+
+	C := Covariance(X)
+	cov := ctx.Decrypt(HomomoCovariance(ctCentered, eval, m, p, ctx.Params, ctx.Ecd, 2), p*p)
+	trace := 0.0
+	for j := 0; j < p; j++ {
+		trace += C[j][j]
+		for k := 0; k < p; k++ {
+			if math.Abs(cov[j*p+k]-C[j][k]) > 1e-4 {
+				t.Fatalf("covariance entry (%d, %d): have %v, want %v", j, k, cov[j*p+k], C[j][k])
+			}
+		}
+	}
+
+	if have := ctx.Decrypt(HomomoTotalVariance(ctCentered, eval, m, p, ctx.Params, ctx.Ecd), 1)[0]; math.Abs(have-trace) > 1e-4 {
+		t.Errorf("total variance %v, want tr(C) = %v", have, trace)
+	}
+}
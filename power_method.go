@@ -6,111 +6,40 @@ import (
 	"github.com/tuneinsight/lattigo/v6/circuits/ckks/lintrans"
 	"github.com/tuneinsight/lattigo/v6/core/rlwe"
 	"github.com/tuneinsight/lattigo/v6/schemes/ckks"
-	"math"
 	"src/eigen/normalize"
+	"src/eigen/tracer"
 )
 
 
 func LinearTrans(A [][]float64, Slots int, n int, ctVec *rlwe.Ciphertext, params ckks.Parameters,
-	ecd *ckks.Encoder, eval *ckks.Evaluator, kgen *rlwe.KeyGenerator, rlk *rlwe.RelinearizationKey,
-	sk *rlwe.SecretKey) (lt lintrans.LinearTransformation, ltEval *lintrans.Evaluator) {
+	ecd *ckks.Encoder, eval tracer.Evaluator) (lt lintrans.LinearTransformation, ltEval *lintrans.Evaluator) {
 
-	
-	diagsA := make([][]float64, n)
-	for k := 0; k < n; k++ {
-		diagsA[k] = make([]float64, n)
-	}
-
-	
-	for i := 0; i < n; i++ {
-		for k := 0; k < n; k++ {
-			diagsA[k][i] = A[i][(i+k)%n]
-		}
-	}
-
-	//for k := 0; k < n; k++ {
-	//	fmt.Printf("the %d-th diagonal: %v\n", k+1, diagsA[k])
-	//}
-
-	nonZeroDiagonals := make([]int, n)
-	for i := 0; i < n; i++ {
-		nonZeroDiagonals[i] = i
-	}
-
-	// We allocate the non-zero diagonals and populate them
-	diagonals := make(lintrans.Diagonals[float64])
-
-	for _, i := range nonZeroDiagonals {
-		tmp := make([]float64, Slots)
-
-		for j := 0; j < n; j++ {
-			tmp[j] = diagsA[i][j]
-		}
-
-		diagonals[i] = tmp
-	}
-
-	ltparams := lintrans.Parameters{
-		DiagonalsIndexList:        diagonals.DiagonalsIndexList(),
-		LevelQ:                    ctVec.Level(),
-		LevelP:                    params.MaxLevelP(),
-		Scale:                     rlwe.NewScale(params.Q()[ctVec.Level()]),
-		LogDimensions:             ctVec.LogDimensions,
-		LogBabyStepGiantStepRatio: 1,
-	}
-	lt = lintrans.NewTransformation(params, ltparams)
-	if err := lintrans.Encode(ecd, diagonals, lt); err != nil {
-		panic(err)
-	}
-
-	galElsLt := lintrans.GaloisElements(params, ltparams)
-
-	ltEval = lintrans.NewEvaluator(eval.WithKey(rlwe.NewMemEvaluationKeySet(rlk, kgen.GenGaloisKeysNew(galElsLt, sk)...)))
-	return lt, ltEval
+	return LinearTransBlocks(A, Slots, n, 1, n, ctVec, params, ecd, eval)
 }
 
 func HomomoMatMutiVec(lt lintrans.LinearTransformation, ltEval *lintrans.Evaluator,
-	ctVec *rlwe.Ciphertext, eval *ckks.Evaluator, n int, LogN int, ctVec0 *rlwe.Ciphertext,
-	rotEval1 *ckks.Evaluator, rot1 int) (ctLintransVec *rlwe.Ciphertext) {
-
-	var err error
+	ctVec *rlwe.Ciphertext, eval tracer.Evaluator, rep *Replicator) (ctLintransVec *rlwe.Ciphertext) {
 
-	logNPow := math.Pow(2, float64(LogN-1))
-	
-	logNPown := logNPow/float64(n) - 1
+	ctVec = rep.Replicate(ctVec, eval)
 
-	for i := 0; i < int(logNPown); i++ {
-		ctVec0, err = eval.AddNew(ctVec, ctVec0)
-		if err != nil {
-			panic(err)
-		}
-		ctVec, err = rotEval1.RotateNew(ctVec, rot1)
-		if err != nil {
-			panic(err)
-		}
-
-	}
-	ctVec = ctVec0
-
-	if err := ltEval.Evaluate(ctVec, lt, ctVec); err != nil {
+	ctLintransVec, err := ltEval.EvaluateNew(ctVec, lt)
+	if err != nil {
 		panic(err)
 	}
 
-	if err = eval.Rescale(ctVec, ctVec); err != nil {
+	if err = eval.Rescale(ctLintransVec, ctLintransVec); err != nil {
 		panic(err)
 	}
 
-	ctLintransVec = ctVec
-
 	return ctLintransVec
 }
 
-func HomomoPowerMethod(evalInnsum *ckks.Evaluator, lt lintrans.LinearTransformation,
-	ltEval *lintrans.Evaluator, ctVec *rlwe.Ciphertext, eval *ckks.Evaluator, dec *rlwe.Decryptor, ecd *ckks.Encoder, Slots int,
+func HomomoPowerMethod(lt lintrans.LinearTransformation,
+	ltEval *lintrans.Evaluator, ctVec *rlwe.Ciphertext, eval tracer.Evaluator, dec *rlwe.Decryptor, ecd *ckks.Encoder, Slots int,
 	max_iter int, batch int, n int, ptf1 *rlwe.Plaintext, ptf2 *rlwe.Plaintext,
 	pta *rlwe.Plaintext, ptb *rlwe.Plaintext, btpEval *bootstrapping.Evaluator,
-	d int, ctVec0 *rlwe.Ciphertext, LogN int, rotEval *ckks.Evaluator, rotEval1 *ckks.Evaluator,
-	rot int, rot1 int) (ctLintransVec *rlwe.Ciphertext, ctNormVec *rlwe.Ciphertext, ctEigenVal *rlwe.Ciphertext) {
+	d int, rep *Replicator, ctDeflVecs []*rlwe.Ciphertext, ptMask *rlwe.Plaintext, workers int,
+	trace *PrecisionTrace) (ctLintransVec *rlwe.Ciphertext, ctNormVec *rlwe.Ciphertext, ctEigenVal *rlwe.Ciphertext) {
 
 	var err error
 	fmt.Println()
@@ -123,7 +52,16 @@ func HomomoPowerMethod(evalInnsum *ckks.Evaluator, lt lintrans.LinearTransformat
 		fmt.Println()
 		fmt.Printf("%2sthe %d-th iteration...", "", i+1)
 		fmt.Println()
-		ctLintransVec = HomomoMatMutiVec(lt, ltEval, ctNormVec, eval, n, LogN, ctVec0, rotEval1, rot1)
+		if len(ctDeflVecs) > 0 {
+			ctNormVec = EnsureLevel(ctNormVec, StageProjection, btpEval)
+			ctNormVec = HomomoProjectOut(ctNormVec, ctDeflVecs, ptMask, eval, ecd, batch, n, workers)
+		}
+		ctNormVec = EnsureLevel(ctNormVec, StagePowerStep, btpEval)
+		ctLintransVec = HomomoMatMutiVec(lt, ltEval, ctNormVec, eval, rep)
+		if trace != nil {
+			trace.Iteration = i + 1
+			trace.RecordMatVec(ctNormVec, ctLintransVec)
+		}
 		LintransVec := dec.DecryptNew(ctLintransVec)
 		LintransVecList := make([]float64, Slots)
 		if err = ecd.Decode(LintransVec, LintransVecList); err != nil {
@@ -136,8 +74,14 @@ func HomomoPowerMethod(evalInnsum *ckks.Evaluator, lt lintrans.LinearTransformat
 		}
 		fmt.Printf("...\n")
 
-		ctVecMulSum := normalize.MulSumVec(evalInnsum, ctLintransVec, ctLintransVec, eval, batch, n)
+		ctVecMulSum := normalize.MulSumVec(eval, ctLintransVec, ctLintransVec, eval, batch, n)
 		cty0 := normalize.LinearApprox(ctVecMulSum, eval, pta, ptb)
+		var step func(i int, cty *rlwe.Ciphertext)
+		if trace != nil {
+			trace.RecordInnerSum(ctLintransVec, ctLintransVec, ctVecMulSum)
+			trace.RecordInitialGuess(ctVecMulSum, cty0)
+			step = trace.NewtonSteps(ctVecMulSum, cty0)
+		}
 
 		y0 := dec.DecryptNew(cty0)
 		y0List := make([]float64, Slots)
@@ -151,7 +95,7 @@ func HomomoPowerMethod(evalInnsum *ckks.Evaluator, lt lintrans.LinearTransformat
 		}
 		fmt.Printf("...\n")
 
-		ctNormVal := normalize.HomomoNewton(ptf1, ptf2, ctVecMulSum, cty0, eval, btpEval, d)
+		ctNormVal := normalize.HomomoNewtonSteps(ptf1, ptf2, ctVecMulSum, cty0, eval, btpEval, d, step)
 
 		NormVal := dec.DecryptNew(ctNormVal)
 		NormValList := make([]float64, Slots)
@@ -166,13 +110,32 @@ func HomomoPowerMethod(evalInnsum *ckks.Evaluator, lt lintrans.LinearTransformat
 		}
 		fmt.Printf("...\n")
 
-		ctNormVec = normalize.NormVect(ctNormVal, ctLintransVec, ctVec0, rotEval, eval, n, rot)
+		ctNormVec = normalize.NormVect(ctNormVal, ctLintransVec, eval, eval, n)
+		if trace != nil {
+			trace.RecordNormalization(ctNormVal, ctLintransVec, ctNormVec)
+		}
+	}
+
+	ctNormVec = EnsureLevel(ctNormVec, StageEigenVal, btpEval)
+	ctEigenVal = HomomoEigenVal(ctLintransVec, ctNormVec, eval, batch, n, ptf1, ptf2, pta, ptb, btpEval, d)
+	if trace != nil {
+		trace.Iteration = 0
+		trace.RecordEigenVal(ctLintransVec, ctNormVec, ctEigenVal)
 	}
 
-	
-	ctLintransNormVec := normalize.MulSumVec(evalInnsum, ctLintransVec, ctNormVec, eval, batch, n)
+	fmt.Println()
+
+	return ctLintransVec, ctNormVec, ctEigenVal
+}
 
-	ctNormVec2 := normalize.MulSumVec(evalInnsum, ctNormVec, ctNormVec, eval, batch, n)
+// HomomoEigenVal returns <Av, v> / <v, v> in slot 0, where ctLintransVec holds Av.
+func HomomoEigenVal(ctLintransVec *rlwe.Ciphertext, ctNormVec *rlwe.Ciphertext,
+	eval tracer.Evaluator, batch int, n int, ptf1 *rlwe.Plaintext, ptf2 *rlwe.Plaintext, pta *rlwe.Plaintext,
+	ptb *rlwe.Plaintext, btpEval *bootstrapping.Evaluator, d int) (ctEigenVal *rlwe.Ciphertext) {
+
+	ctLintransNormVec := normalize.MulSumVec(eval, ctLintransVec, ctNormVec, eval, batch, n)
+
+	ctNormVec2 := normalize.MulSumVec(eval, ctNormVec, ctNormVec, eval, batch, n)
 
 	ctNormVec4, err := eval.MulRelinNew(ctNormVec2, ctNormVec2)
 	if err != nil {
@@ -194,7 +157,5 @@ func HomomoPowerMethod(evalInnsum *ckks.Evaluator, lt lintrans.LinearTransformat
 		panic(err)
 	}
 
-	fmt.Println()
-
-	return ctLintransVec, ctNormVec, ctEigenVal
+	return ctEigenVal
 }
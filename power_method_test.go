@@ -0,0 +1,203 @@
+package main
+
+import (
+	"math"
+	"src/eigen/fixture"
+	"testing"
+)
+
+// Power method iterations of the tests, as in main.
+const testIters = 4
+
+// Matrices of known spectrum. Their dominant eigenvalues keep the inputs of
+// HomomoNewton within the range of the initial guess, and their eigengaps let
+// testIters iterations converge.
+// spectrumCase is a symmetric matrix and its eigenvalues, in decreasing order.
+type spectrumCase struct {
+	name string
+	A    [][]float64
+	vals []float64
+}
+
+var spectrumCases = []spectrumCase{
+	{"diagonal", diagonalMatrix(9, 3, 1, 0.5), []float64{9, 3, 1, 0.5}},
+	// Eigenvector (1, 1, 1, 1) / 2 for 12
+	{"circulant", circulantMatrix(5, 3, 1, 3), []float64{12, 4, 4, 0}},
+	// 8 u u^T for the unit u = (1, 2, 2, 4) / 5
+	{"rank-1", subOuter(diagonalMatrix(0, 0, 0, 0), -8, []float64{0.2, 0.4, 0.4, 0.8}), []float64{8, 0, 0, 0}},
+	// In the basis of a Householder reflection
+	{"repeated", householderConjugate(diagonalMatrix(7, 7, 1, 1), []float64{0.5, 0.5, 0.5, 0.5}),
+		[]float64{7, 7, 1, 1}},
+}
+
+func diagonalMatrix(diag ...float64) (A [][]float64) {
+	A = make([][]float64, len(diag))
+	for i := range A {
+		A[i] = make([]float64, len(diag))
+		A[i][i] = diag[i]
+	}
+	return A
+}
+
+func circulantMatrix(row ...float64) (A [][]float64) {
+	n := len(row)
+	A = make([][]float64, n)
+	for i := range A {
+		A[i] = make([]float64, n)
+		for j := range A[i] {
+			A[i][j] = row[(j-i+n)%n]
+		}
+	}
+	return A
+}
+
+// householderConjugate returns Q A Q^T for Q = I - 2 w w^T and a unit w.
+func householderConjugate(A [][]float64, w []float64) (B [][]float64) {
+	n := len(A)
+	Q := subOuter(diagonalMatrix(make([]float64, n)...), 2, w)
+	for i := range Q {
+		Q[i][i] += 1
+	}
+	B = make([][]float64, n)
+	for i := range B {
+		B[i] = make([]float64, n)
+		for j := range B[i] {
+			for k := 0; k < n; k++ {
+				for l := 0; l < n; l++ {
+					B[i][j] += Q[i][k] * A[k][l] * Q[j][l]
+				}
+			}
+		}
+	}
+	return B
+}
+
+// eigenResidual returns ||A u - lambda u|| / |lambda| for u = v / ||v||, which
+// is small when v lies in the eigenspace of lambda, repeated or not.
+func eigenResidual(A [][]float64, v []float64, lambda float64) float64 {
+	u := scale(v, 1/math.Sqrt(dot(v, v)))
+	r := matVec(A, u)
+	for i := range r {
+		r[i] -= lambda * u[i]
+	}
+	return math.Sqrt(dot(r, r)) / math.Abs(lambda)
+}
+
+// powerMethod runs HomomoPowerMethod from vec and returns the decrypted
+// eigenvector and eigenvalue.
+func powerMethod(ctx *benchContext, A [][]float64, vec []float64) (eigenVec []float64, eigenVal float64) {
+	return powerMethodIters(ctx, A, vec, testIters)
+}
+
+// powerMethodIters is powerMethod with max_iter iterations.
+func powerMethodIters(ctx *benchContext, A [][]float64, vec []float64, max_iter int) (eigenVec []float64,
+	eigenVal float64) {
+
+	n := len(A)
+	eval := ctx.eval(n)
+	Slots := ctx.Params.MaxSlots()
+	ctVec := ctx.Encrypt(vec, ctx.Params.MaxLevel())
+	lt, ltEval := LinearTrans(A, Slots, n, ctVec, ctx.Params, ctx.Ecd, eval)
+	_, ctEigenVec, ctEigenVal := HomomoPowerMethod(lt, ltEval, ctVec, eval, ctx.Dec, ctx.Ecd, Slots, max_iter, 1, n,
+		ctx.Encode(fixture.F1), ctx.Encode(fixture.F2), ctx.Encode(fixture.A), ctx.Encode(fixture.B), ctx.BtpEval, fixture.D,
+		NewReplicator(n, MatVecReplicas(Slots, n)), nil, nil, 1, nil)
+	return ctx.Decrypt(ctEigenVec, n), ctx.Decrypt(ctEigenVal, 1)[0]
+}
+
+// checkEigenpair compares the decrypted eigenpair with the plaintext power
+// method, which shares its Newton approximations, and with the exact eigenvalue
+// lambda. The eigenvalue is off lambda by the 5% of the six Newton steps on
+// <v, v>^2 = 1 of HomomoEigenVal, from an initial guess made for larger inputs.
+func checkEigenpair(t *testing.T, A [][]float64, eigenVec []float64, eigenVal float64, refVec []float64,
+	refVal float64, lambda float64) {
+
+	t.Helper()
+	if err := math.Abs(eigenVal-refVal) / math.Abs(refVal); err > 1e-3 {
+		t.Errorf("eigenvalue %v, plaintext power method %v: relative error %.2e", eigenVal, refVal, err)
+	}
+	if a := angle(eigenVec, refVec); a > 0.5 {
+		t.Errorf("eigenvector at %.3f degrees of the plaintext power method", a)
+	}
+	if err := math.Abs(eigenVal-lambda) / math.Abs(lambda); err > 0.08 {
+		t.Errorf("eigenvalue %v, exact %v: relative error %.2e", eigenVal, lambda, err)
+	}
+	if r := eigenResidual(A, eigenVec, lambda); r > 0.05 {
+		t.Errorf("eigenvector residual %.2e for the exact eigenvalue %v", r, lambda)
+	}
+}
+
+func TestLinearTrans(t *testing.T) {
+	ctx := getBenchContext()
+	cases := append(spectrumCases[:len(spectrumCases):len(spectrumCases)], spectrumCase{"random n=5", benchMatrix(5), nil})
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			n := len(tc.A)
+			eval := ctx.eval(n)
+			vec := StartVectors(0, 0, 1, n)[0]
+			ctVec := ctx.Encrypt(vec, ctx.Params.MaxLevel())
+			lt, ltEval := LinearTrans(tc.A, ctx.Params.MaxSlots(), n, ctVec, ctx.Params, ctx.Ecd, eval)
+			ctLintransVec := HomomoMatMutiVec(lt, ltEval, ctVec, eval, NewReplicator(n, MatVecReplicas(ctx.Params.MaxSlots(), n)))
+
+			have, want := ctx.Decrypt(ctLintransVec, n), matVec(tc.A, vec)
+			for i := range want {
+				if math.Abs(have[i]-want[i]) > 1e-4 {
+					t.Fatalf("slot %d: have %v, want %v", i, have[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestHomomoPowerMethod(t *testing.T) {
+	if testing.Short() {
+		t.Skip("bootstraps about 30 times per case")
+	}
+	ctx := getBenchContext()
+	for _, tc := range spectrumCases {
+		t.Run(tc.name, func(t *testing.T) {
+			vec := StartVectors(0, 0, 1, len(tc.A))[0]
+			eigenVec, eigenVal := powerMethod(ctx, tc.A, vec)
+			refVec, refVal := ReferencePowerMethod(tc.A, vec, testIters, false, fixture.D, fixture.A, fixture.B, fixture.F1, fixture.F2)
+			exactVals, _ := JacobiEigen(tc.A)
+			checkEigenpair(t, tc.A, eigenVec, eigenVal, refVec, refVal, exactVals[0])
+		})
+	}
+}
+
+func TestHomomoEigenShift(t *testing.T) {
+	if testing.Short() {
+		t.Skip("bootstraps about 30 times per eigenpair")
+	}
+	ctx := getBenchContext()
+	for _, tc := range spectrumCases[:2] {
+		t.Run(tc.name, func(t *testing.T) {
+			n := len(tc.A)
+			vec := StartVectors(0, 0, 1, n)[0]
+			eigenVec, eigenVal := powerMethod(ctx, tc.A, vec)
+
+			ctShiftMat, _ := HomomoEigenShift(ctx.encryptMatrix(tc.A),
+				ctx.Encrypt(eigenVec, ctx.Params.MaxLevel()), ctx.Encrypt([]float64{eigenVal}, ctx.Params.MaxLevel()),
+				ctx.eval(n), n, 1, ctx.Params, ctx.Ecd, ctx.BtpEval, 1, nil)
+
+			shifted := subOuter(tc.A, eigenVal, eigenVec)
+			have := ctx.Decrypt(ctShiftMat, n*n)
+			deflated := make([][]float64, n)
+			for i := range deflated {
+				deflated[i] = have[i*n : (i+1)*n]
+				for j := range deflated[i] {
+					if math.Abs(deflated[i][j]-shifted[i][j]) > 1e-4 {
+						t.Fatalf("entry (%d, %d): have %v, want %v", i, j, deflated[i][j], shifted[i][j])
+					}
+				}
+			}
+
+			// The second eigenpair of A is the first of the deflated matrix
+			vec = StartVectors(0, 1, 1, n)[0]
+			eigenVec, eigenVal = powerMethod(ctx, deflated, vec)
+			refVec, refVal := ReferencePowerMethod(deflated, vec, testIters, false, fixture.D, fixture.A, fixture.B, fixture.F1, fixture.F2)
+			exactVals, _ := JacobiEigen(tc.A)
+			checkEigenpair(t, tc.A, eigenVec, eigenVal, refVec, refVal, exactVals[1])
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Preconditioning modes of the public factor c that A is scaled by before encryption.
+const (
+	PreconditionNone      = "none"
+	PreconditionTrace     = "trace"
+	PreconditionFrobenius = "frobenius"
+)
+
+// PreconditionNorm bounds the dominant eigenvalue of the scaled matrix.
+const PreconditionNorm = 8.0
+
+// PreconditionFactor returns the public factor c of A in the given mode.
+func PreconditionFactor(A [][]float64, mode string) (c float64) {
+	var norm float64
+	switch mode {
+	case PreconditionNone:
+		return 1
+	case PreconditionTrace:
+		for i := range A {
+			norm += A[i][i]
+		}
+		norm = math.Abs(norm)
+	case PreconditionFrobenius:
+		norm = math.Sqrt(frobenius2(A))
+	default:
+		panic(fmt.Errorf("unknown preconditioning %q, expected none, trace or frobenius", mode))
+	}
+	if norm == 0 {
+		panic(fmt.Errorf("cannot precondition by the %s of A, which is zero", mode))
+	}
+	return PreconditionNorm / norm
+}
+
+// ScaleMatrix returns c*A.
+func ScaleMatrix(A [][]float64, c float64) (B [][]float64) {
+	B = make([][]float64, len(A))
+	for i := range A {
+		B[i] = scale(A[i], c)
+	}
+	return B
+}
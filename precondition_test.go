@@ -0,0 +1,95 @@
+package main
+
+import (
+	"math"
+	"src/eigen/fixture"
+	"testing"
+)
+
+func TestPreconditionFactor(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		A    [][]float64
+		mode string
+		want float64
+	}{
+		{"none", diagonalMatrix(3, 4), PreconditionNone, 1},
+		{"none of zero", diagonalMatrix(0, 0), PreconditionNone, 1},
+		{"trace", diagonalMatrix(1, 2, 5), PreconditionTrace, PreconditionNorm / 8},
+		{"negative trace", diagonalMatrix(-1, -3), PreconditionTrace, PreconditionNorm / 4},
+		{"trace of off-diagonal", [][]float64{{1, 7}, {7, 3}}, PreconditionTrace, PreconditionNorm / 4},
+		{"frobenius", diagonalMatrix(3, 4), PreconditionFrobenius, PreconditionNorm / 5},
+		{"frobenius of off-diagonal", [][]float64{{0, 3}, {3, 0}}, PreconditionFrobenius,
+			PreconditionNorm / math.Sqrt(18)},
+		{"frobenius of traceless", diagonalMatrix(1, -1), PreconditionFrobenius, PreconditionNorm / math.Sqrt(2)},
+		{"Air scale", diagonalMatrix(4e-8, 1e-8), PreconditionTrace, PreconditionNorm / 5e-8},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if have := PreconditionFactor(tc.A, tc.mode); math.Abs(have-tc.want) > 1e-12*tc.want {
+				t.Errorf("have %v, want %v", have, tc.want)
+			}
+		})
+	}
+}
+
+func TestPreconditionFactorDegenerate(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		A    [][]float64
+		mode string
+	}{
+		{"trace of zero", diagonalMatrix(0, 0), PreconditionTrace},
+		{"frobenius of zero", diagonalMatrix(0, 0), PreconditionFrobenius},
+		{"trace of traceless", diagonalMatrix(1, -1), PreconditionTrace},
+		{"empty", nil, PreconditionFrobenius},
+		{"unknown mode", diagonalMatrix(1, 2), "spectral"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("no panic")
+				}
+			}()
+			PreconditionFactor(tc.A, tc.mode)
+		})
+	}
+}
+
+func TestScaleMatrix(t *testing.T) {
+	A := [][]float64{{1, -2}, {-2, 4}}
+	B := ScaleMatrix(A, 0.5)
+	want := [][]float64{{0.5, -1}, {-1, 2}}
+	for i := range want {
+		for j := range want[i] {
+			if B[i][j] != want[i][j] {
+				t.Errorf("B[%d][%d] = %v, want %v", i, j, B[i][j], want[i][j])
+			}
+		}
+	}
+	if A[0][0] != 1 || A[1][1] != 4 {
+		t.Errorf("A modified to %v", A)
+	}
+}
+
+// TestPreconditionRoundTrip runs the power method on a matrix with the
+// eigenvalues of Air, around 1e-8, scaled by its trace factor, and undoes the
+// scaling on the decrypted eigenvalue as main does.
+func TestPreconditionRoundTrip(t *testing.T) {
+	if testing.Short() {
+		t.Skip("bootstraps about 30 times per mode")
+	}
+	ctx := getBenchContext()
+	A := householderConjugate(diagonalMatrix(4e-8, 1e-8, 5e-9, 2e-9), []float64{0.5, 0.5, 0.5, 0.5})
+	vec := []float64{0.6, -0.3, -0.8, 0.4}
+
+	for _, mode := range []string{PreconditionTrace, PreconditionFrobenius} {
+		t.Run(mode, func(t *testing.T) {
+			c := PreconditionFactor(A, mode)
+			scaledA := ScaleMatrix(A, c)
+			eigenVec, eigenVal := powerMethod(ctx, scaledA, vec)
+			refVec, refVal := ReferencePowerMethod(scaledA, vec, testIters, false, fixture.D, fixture.A, fixture.B,
+				fixture.F1, fixture.F2)
+			checkEigenpair(t, A, eigenVec, eigenVal/c, refVec, refVal/c, 4e-8)
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/csv"
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+	"github.com/tuneinsight/lattigo/v6/schemes/ckks"
+	"os"
+	"src/eigen/normalize"
+	"src/eigen/tracer"
+	"strconv"
+)
+
+// ProjectGaloisElements returns the Galois elements of the projection onto k components.
+func ProjectGaloisElements(params ckks.Parameters, Slots int, p int, k int) (galEls []uint64) {
+	stride := MultiStartStride(p)
+	galEls = append(galEls, ReplicatorGaloisElements(params, p, 2)...)
+	galEls = append(galEls, params.GaloisElementsForReplicate(stride, Slots/stride)...)
+	galEls = append(galEls, params.GaloisElementsForInnerSum(1, p)...)
+	for i := 1; i < k; i++ {
+		galEls = append(galEls, params.GaloisElement(-i))
+	}
+	return galEls
+}
+
+// HomomoProject returns the scores of the block-packed samples on the plaintext components.
+func HomomoProject(components [][]float64, ctSamples *rlwe.Ciphertext, eval tracer.Evaluator, p int, Slots int,
+	params ckks.Parameters, ecd *ckks.Encoder) (ctScores *rlwe.Ciphertext) {
+
+	W := make([][]float64, p)
+	for i := range W {
+		W[i] = make([]float64, p)
+		if i < len(components) {
+			copy(W[i], components[i])
+		}
+	}
+
+	stride := MultiStartStride(p)
+	lt, ltEval := LinearTransBlocks(W, Slots, p, Slots/stride, stride, ctSamples, params, ecd, eval)
+	return HomomoMatMutiVec(lt, ltEval, ctSamples, eval, NewReplicator(p, 2))
+}
+
+// HomomoProjectEncrypted returns the scores of the block-packed samples on the encrypted components.
+func HomomoProjectEncrypted(ctComponents []*rlwe.Ciphertext, ctSamples *rlwe.Ciphertext, eval tracer.Evaluator,
+	p int, Slots int, params ckks.Parameters, ecd *ckks.Encoder, workers int) (ctScores *rlwe.Ciphertext) {
+
+	if err := CheckGaloisKeys(eval, ProjectGaloisElements(params, Slots, p, len(ctComponents))); err != nil {
+		panic(err)
+	}
+
+	stride := MultiStartStride(p)
+	blocks := Slots / stride
+	ptStart := BlockPlaintext(1, 1, blocks, stride, Slots, params, ecd)
+
+	ctTerms := ParallelEval(workers, len(ctComponents), eval, ecd, func(i int, eval tracer.Evaluator, ecd *ckks.Encoder) *rlwe.Ciphertext {
+		ctComponent := ctComponents[i].CopyNew()
+		if err := eval.Replicate(ctComponent, stride, blocks, ctComponent); err != nil {
+			panic(err)
+		}
+
+		ctScore := normalize.MulSumVec(eval, ctComponent, ctSamples, eval, 1, p)
+		ctScore, err := eval.MulRelinNew(ctScore, ptStart)
+		if err != nil {
+			panic(err)
+		}
+		if err = eval.Rescale(ctScore, ctScore); err != nil {
+			panic(err)
+		}
+
+		if i > 0 {
+			if ctScore, err = eval.RotateNew(ctScore, -i); err != nil {
+				panic(err)
+			}
+		}
+		return ctScore
+	})
+	return SumInOrder(ctTerms, eval)
+}
+
+// DecryptScores decrypts the k scores of the first count blocks of ctScores.
+func DecryptScores(ctScores *rlwe.Ciphertext, count int, k int, stride int, dec *rlwe.Decryptor,
+	ecd *ckks.Encoder, Slots int) (scores [][]float64) {
+
+	scoresVec := make([]float64, Slots)
+	if err := ecd.Decode(dec.DecryptNew(ctScores), scoresVec); err != nil {
+		panic(err)
+	}
+
+	scores = make([][]float64, count)
+	for b := range scores {
+		scores[b] = append([]float64(nil), scoresVec[b*stride:b*stride+k]...)
+	}
+	return scores
+}
+
+// WriteScores writes one row of scores per sample.
+func WriteScores(path string, scores [][]float64) {
+	file, err := os.Create(path)
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	for _, sample := range scores {
+		var row []string
+		for _, score := range sample {
+			row = append(row, strconv.FormatFloat(score, 'f', 20, 64))
+		}
+		if err = writer.Write(row); err != nil {
+			panic(err)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+	"math"
+	"testing"
+)
+
+func TestHomomoProject(t *testing.T) {
+	ctx := getBenchContext()
+
+	p, Slots := 4, ctx.Params.MaxSlots()
+	stride := MultiStartStride(p)
+	_, eigenVecs := JacobiEigen(spectrumCases[1].A)
+	components := eigenVecs[:2]
+	samples := StartVectors(0, 0, 5, p)
+
+	eval := ctx.evalWith(PipelineOptions{N: p, Project: len(components)})
+	ctSamples := ctx.Encrypt(EncodeBlocks(samples, Slots, stride), ctx.Params.MaxLevel())
+
+	ctComponents := make([]*rlwe.Ciphertext, len(components))
+	for i, component := range components {
+		ctComponents[i] = ctx.Encrypt(component, ctx.Params.MaxLevel())
+	}
+
+	for _, tc := range []struct {
+		name     string
+		ctScores *rlwe.Ciphertext
+	}{
+		{"plaintext components", HomomoProject(components, ctSamples, eval, p, Slots, ctx.Params, ctx.Ecd)},
+		{"encrypted components", HomomoProjectEncrypted(ctComponents, ctSamples, eval, p, Slots, ctx.Params, ctx.Ecd, 2)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			scores := DecryptScores(tc.ctScores, len(samples), len(components), stride, ctx.Dec, ctx.Ecd, Slots)
+			for b, sample := range samples {
+				for i, component := range components {
+					if want := dot(sample, component); math.Abs(scores[b][i]-want) > 1e-4 {
+						t.Errorf("sample %d, component %d: score %v, want %v", b, i, scores[b][i], want)
+					}
+				}
+			}
+		})
+	}
+}
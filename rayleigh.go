@@ -0,0 +1,80 @@
+package main
+
+import (
+	"github.com/tuneinsight/lattigo/v6/circuits/ckks/bootstrapping"
+	"github.com/tuneinsight/lattigo/v6/circuits/ckks/lintrans"
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+	"src/eigen/normalize"
+	"src/eigen/tracer"
+)
+
+// HomomoRayleighQuotient returns <A v, v> times the slot 0 of ptMask in slot 0, zero elsewhere.
+func HomomoRayleighQuotient(ctLintransVec *rlwe.Ciphertext, ctVec *rlwe.Ciphertext,
+	ptMask *rlwe.Plaintext, eval tracer.Evaluator, batch int, n int) (ctQuotient *rlwe.Ciphertext) {
+
+	ctInner := normalize.MulSumVec(eval, ctLintransVec, ctVec, eval, batch, n)
+
+	ctQuotient, err := eval.MulRelinNew(ctInner, ptMask)
+	if err != nil {
+		panic(err)
+	}
+	if err = eval.Rescale(ctQuotient, ctQuotient); err != nil {
+		panic(err)
+	}
+	return ctQuotient
+}
+
+// RayleighMaxShift bounds the shift factor so that the dominant eigenvector of a PSD A still dominates.
+const RayleighMaxShift = 0.5
+
+// HomomoRayleighPowerMethod iterates with A - shift*theta*I, theta the Rayleigh quotient of the iterate.
+func HomomoRayleighPowerMethod(lt lintrans.LinearTransformation,
+	ltEval *lintrans.Evaluator, ctVec *rlwe.Ciphertext, eval tracer.Evaluator,
+	max_iter int, batch int, n int, ptf1 *rlwe.Plaintext, ptf2 *rlwe.Plaintext,
+	pta *rlwe.Plaintext, ptb *rlwe.Plaintext, ptOne *rlwe.Plaintext, ptShift *rlwe.Plaintext, btpEval *bootstrapping.Evaluator,
+	d int, rep *Replicator, emit func(i int, ctNormVec *rlwe.Ciphertext, ctQuotient *rlwe.Ciphertext)) (ctLintransVec *rlwe.Ciphertext,
+	ctNormVec *rlwe.Ciphertext, ctEigenVal *rlwe.Ciphertext) {
+
+	var err error
+	ctNormVec = ctVec
+	for i := 0; i < max_iter; i++ {
+		if ptShift != nil && i > 0 {
+			ctNormVec = EnsureLevel(ctNormVec, StageRayleighStep, btpEval)
+		} else {
+			ctNormVec = EnsureLevel(ctNormVec, StagePowerStep, btpEval)
+		}
+
+		ctLintransVec = HomomoMatMutiVec(lt, ltEval, ctNormVec, eval, rep)
+
+		// The start vector is not normalized, so the quotient is only defined from the second step on
+		if i > 0 && emit != nil {
+			emit(i, ctNormVec, HomomoRayleighQuotient(ctLintransVec, ctNormVec, ptOne, eval, batch, n))
+		}
+
+		if i > 0 && ptShift != nil {
+			ctShiftVal := HomomoRayleighQuotient(ctLintransVec, ctNormVec, ptShift, eval, batch, n)
+			ctShiftVec := normalize.NormVect(ctShiftVal, ctNormVec, eval, eval, n)
+
+			if ctLintransVec, err = eval.SubNew(ctLintransVec, ctShiftVec); err != nil {
+				panic(err)
+			}
+		}
+
+		ctVecMulSum := normalize.MulSumVec(eval, ctLintransVec, ctLintransVec, eval, batch, n)
+		cty0 := normalize.LinearApprox(ctVecMulSum, eval, pta, ptb)
+		ctNormVal := normalize.HomomoNewton(ptf1, ptf2, ctVecMulSum, cty0, eval, btpEval, d)
+
+		ctNormVec = normalize.NormVect(ctNormVal, ctLintransVec, eval, eval, n)
+	}
+
+	// One more product gives the quotient of the final iterate
+	ctNormVec = EnsureLevel(ctNormVec, StageRayleighQuotient, btpEval)
+	ctLintransVec = HomomoMatMutiVec(lt, ltEval, ctNormVec, eval, rep)
+	ctEigenVal = HomomoRayleighQuotient(ctLintransVec, ctNormVec, ptOne, eval, batch, n)
+
+	if emit != nil {
+		emit(max_iter, ctNormVec, ctEigenVal)
+	}
+
+	return ctLintransVec, ctNormVec, ctEigenVal
+}
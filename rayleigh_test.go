@@ -0,0 +1,88 @@
+package main
+
+import (
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+	"math"
+	"src/eigen/fixture"
+	"testing"
+)
+
+// rayleighPowerMethod runs HomomoRayleighPowerMethod from vec with the shift
+// factor shift and returns the decrypted eigenvector and eigenvalue, and the
+// decrypted iterates from the second one on.
+func rayleighPowerMethod(ctx *benchContext, A [][]float64, vec []float64, max_iter int,
+	shift float64) (eigenVec []float64, eigenVal float64, iterates [][]float64) {
+
+	n := len(A)
+	eval := ctx.eval(n)
+	Slots := ctx.Params.MaxSlots()
+	ctVec := ctx.Encrypt(vec, ctx.Params.MaxLevel())
+	lt, ltEval := LinearTrans(A, Slots, n, ctVec, ctx.Params, ctx.Ecd, eval)
+	emit := func(i int, ctNormVec *rlwe.Ciphertext, _ *rlwe.Ciphertext) {
+		iterates = append(iterates, ctx.Decrypt(ctNormVec, n))
+	}
+	_, ctEigenVec, ctEigenVal := HomomoRayleighPowerMethod(lt, ltEval, ctVec, eval, max_iter, 1, n,
+		ctx.Encode(fixture.F1), ctx.Encode(fixture.F2), ctx.Encode(fixture.A), ctx.Encode(fixture.B),
+		ctx.Encode(1), ctx.Encode(shift), ctx.BtpEval, fixture.D, NewReplicator(n, MatVecReplicas(Slots, n)), emit)
+	return ctx.Decrypt(ctEigenVec, n), ctx.Decrypt(ctEigenVal, 1)[0], iterates
+}
+
+func TestHomomoRayleighPowerMethodShift(t *testing.T) {
+	if testing.Short() {
+		t.Skip("bootstraps about 60 times")
+	}
+	ctx := getBenchContext()
+
+	// 9, 6, 5, 4: the plain ratio 6/9 drops to about 2/5 with a shift of 0.45
+	A := householderConjugate(diagonalMatrix(9, 6, 5, 4), []float64{0.5, 0.5, 0.5, 0.5})
+	exactVals, exactVecs := JacobiEigen(A)
+	vec := []float64{0.6, -0.3, -0.8, 0.4}
+
+	plainVec, _ := powerMethod(ctx, A, vec)
+	shiftVec, shiftVal, _ := rayleighPowerMethod(ctx, A, vec, testIters-1, 0.45)
+
+	plainAngle, shiftAngle := angle(plainVec, exactVecs[0]), angle(shiftVec, exactVecs[0])
+	if shiftAngle >= plainAngle {
+		t.Errorf("%d shifted iterations at %.3f degrees of the eigenvector, %d plain ones at %.3f", testIters-1,
+			shiftAngle, testIters, plainAngle)
+	}
+	// The Rayleigh quotient has no Newton inverse of ||v||^4 and is biased
+	// only by the angle of the iterate
+	if r := eigenResidual(A, shiftVec, exactVals[0]); r > 0.05 {
+		t.Errorf("eigenvector residual %.2e for the exact eigenvalue %v", r, exactVals[0])
+	}
+	if err := math.Abs(shiftVal-exactVals[0]) / exactVals[0]; err > 0.02 {
+		t.Errorf("Rayleigh quotient %v, exact %v: relative error %.2e", shiftVal, exactVals[0], err)
+	}
+}
+
+// TestHomomoRayleighPowerMethodIterations counts the iterations to 2 degrees of
+// the eigenvector: 4 shifted ones against 7 of the plain power method.
+func TestHomomoRayleighPowerMethodIterations(t *testing.T) {
+	if testing.Short() {
+		t.Skip("bootstraps about 40 times")
+	}
+	ctx := getBenchContext()
+
+	A := householderConjugate(diagonalMatrix(9, 6, 5, 4), []float64{0.5, 0.5, 0.5, 0.5})
+	_, exactVecs := JacobiEigen(A)
+	vec := []float64{0.6, -0.3, -0.8, 0.4}
+	const tolerance = 2.0
+
+	_, _, iterates := rayleighPowerMethod(ctx, A, vec, 5, 0.45)
+	shiftIters := 0
+	for i, iterate := range iterates {
+		if angle(iterate, exactVecs[0]) < tolerance {
+			shiftIters = i + 1
+			break
+		}
+	}
+	if shiftIters == 0 {
+		t.Fatalf("%d shifted iterations not within %v degrees of the eigenvector", len(iterates), tolerance)
+	}
+
+	plainVec, _ := powerMethodIters(ctx, A, vec, shiftIters)
+	if a := angle(plainVec, exactVecs[0]); a < tolerance {
+		t.Errorf("%d plain iterations at %.3f degrees of the eigenvector, as fast as the shifted ones", shiftIters, a)
+	}
+}
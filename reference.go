@@ -0,0 +1,278 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// StartVectors returns the count random start vectors of length n of the i-th eigenpair under seed.
+func StartVectors(seed int64, i int, count int, n int) (vecs [][]float64) {
+	source := int64(i + 5)
+	if seed != 0 {
+		source = int64(binary.LittleEndian.Uint64(seedKey(seed, "start vectors", i)))
+	}
+	r := rand.New(rand.NewSource(source))
+	vecs = make([][]float64, count)
+	for s := range vecs {
+		vecs[s] = make([]float64, n)
+		for j := range vecs[s] {
+			vecs[s][j] = 2*r.Float64() - 1
+		}
+	}
+	return vecs
+}
+
+// ReferencePowerMethod is HomomoPowerMethod, or HomomoSignedPowerMethod if squared, in float64.
+func ReferencePowerMethod(A [][]float64, vec []float64, max_iter int, squared bool, d int, a float64, b float64,
+	f1 float64, f2 float64) (eigenVec []float64, eigenVal float64) {
+
+	eigenVec = vec
+	var lintransVec []float64
+	for i := 0; i < max_iter; i++ {
+		lintransVec = matVec(A, eigenVec)
+		if squared {
+			lintransVec = matVec(A, lintransVec)
+		}
+		eigenVec = scale(lintransVec, ReferenceNewton(dot(lintransVec, lintransVec), d, a, b, f1, f2))
+	}
+	if squared {
+		return eigenVec, ReferenceEigenVal(A, eigenVec, d, a, b, f1, f2)
+	}
+	normVec2 := dot(eigenVec, eigenVec)
+	return eigenVec, dot(lintransVec, eigenVec) * ReferenceNewton(normVec2*normVec2, d, a, b, f1, f2)
+}
+
+// ReferenceEigenVal is HomomoEigenVal of the product of A by v in float64.
+func ReferenceEigenVal(A [][]float64, v []float64, d int, a float64, b float64, f1 float64,
+	f2 float64) (eigenVal float64) {
+
+	normVec2 := dot(v, v)
+	return dot(matVec(A, v), v) * ReferenceNewton(normVec2*normVec2, d, a, b, f1, f2)
+}
+
+// ReferenceNewton is LinearApprox followed by HomomoNewton in float64.
+func ReferenceNewton(x float64, d int, a float64, b float64, f1 float64, f2 float64) (y float64) {
+	y = a*x + b
+	for i := 0; i < d; i++ {
+		y = f2*y - f1*x*y*y*y
+	}
+	return y
+}
+
+// ReferenceSVD runs ReferencePowerMethod from each of startVecs with the Hotelling deflation in between.
+func ReferenceSVD(A [][]float64, startVecs [][]float64, max_iter int, squared bool, d int, a float64, b float64,
+	f1 float64, f2 float64) (eigenVecs [][]float64, eigenVals []float64) {
+
+	for _, vec := range startVecs {
+		eigenVec, eigenVal := ReferencePowerMethod(A, vec, max_iter, squared, d, a, b, f1, f2)
+		eigenVecs = append(eigenVecs, eigenVec)
+		eigenVals = append(eigenVals, eigenVal)
+		A = subOuter(A, eigenVal, eigenVec)
+	}
+	return eigenVecs, eigenVals
+}
+
+// JacobiEigen returns the eigenpairs of the symmetric part of A by decreasing magnitude.
+func JacobiEigen(A [][]float64) (eigenVals []float64, eigenVecs [][]float64) {
+	n := len(A)
+	a := make([][]float64, n)
+	v := make([][]float64, n)
+	for i := range a {
+		a[i] = make([]float64, n)
+		v[i] = make([]float64, n)
+		v[i][i] = 1
+		for j := range a[i] {
+			a[i][j] = (A[i][j] + A[j][i]) / 2
+		}
+	}
+
+	for sweep := 0; sweep < 64; sweep++ {
+		off, norm := 0.0, 0.0
+		for p := 0; p < n; p++ {
+			for q := 0; q < n; q++ {
+				norm += a[p][q] * a[p][q]
+				if p != q {
+					off += a[p][q] * a[p][q]
+				}
+			}
+		}
+		if off <= 1e-30*norm {
+			break
+		}
+
+		for p := 0; p < n-1; p++ {
+			for q := p + 1; q < n; q++ {
+				if a[p][q] == 0 {
+					continue
+				}
+				theta := (a[q][q] - a[p][p]) / (2 * a[p][q])
+				t := 1 / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+				if theta < 0 {
+					t = -t
+				}
+				c := 1 / math.Sqrt(t*t+1)
+				s := t * c
+				for k := 0; k < n; k++ {
+					akp, akq := a[k][p], a[k][q]
+					a[k][p], a[k][q] = c*akp-s*akq, s*akp+c*akq
+				}
+				for k := 0; k < n; k++ {
+					apk, aqk := a[p][k], a[q][k]
+					a[p][k], a[q][k] = c*apk-s*aqk, s*apk+c*aqk
+				}
+				for k := 0; k < n; k++ {
+					vkp, vkq := v[k][p], v[k][q]
+					v[k][p], v[k][q] = c*vkp-s*vkq, s*vkp+c*vkq
+				}
+			}
+		}
+	}
+
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return math.Abs(a[order[i]][order[i]]) > math.Abs(a[order[j]][order[j]])
+	})
+	for _, k := range order {
+		eigenVals = append(eigenVals, a[k][k])
+		vec := make([]float64, n)
+		for i := range vec {
+			vec[i] = v[i][k]
+		}
+		eigenVecs = append(eigenVecs, vec)
+	}
+	return eigenVals, eigenVecs
+}
+
+// SpectrumOrder reorders the exact eigenpairs in the order the spectrum mode finds them.
+func SpectrumOrder(eigenVals []float64, eigenVecs [][]float64, spectrum string, target float64) ([]float64, [][]float64) {
+	order := make([]int, len(eigenVals))
+	for i := range order {
+		order[i] = i
+	}
+	switch spectrum {
+	case SpectrumSmallest:
+		sort.SliceStable(order, func(i, j int) bool { return eigenVals[order[i]] < eigenVals[order[j]] })
+	case SpectrumNearest:
+		sort.SliceStable(order, func(i, j int) bool {
+			return math.Abs(eigenVals[order[i]]-target) < math.Abs(eigenVals[order[j]]-target)
+		})
+	}
+
+	vals := make([]float64, len(order))
+	vecs := make([][]float64, len(order))
+	for i, k := range order {
+		vals[i], vecs[i] = eigenVals[k], eigenVecs[k]
+	}
+	return vals, vecs
+}
+
+// PrintAccuracyReport compares the decrypted eigenpairs of A with the plaintext and exact ones.
+func PrintAccuracyReport(A [][]float64, eigenVecs [][]float64, eigenVals []float64, refVecs [][]float64,
+	refVals []float64, spectrum string, target float64) {
+
+	k := len(eigenVals)
+	exactVals, exactVecs := JacobiEigen(A)
+	exactVals, exactVecs = SpectrumOrder(exactVals, exactVecs, spectrum, target)
+
+	fmt.Println()
+	fmt.Println("Accuracy report (decrypted vs plaintext power method vs exact)...")
+	fmt.Printf("%2s%-3s %14s %14s %14s %11s %11s %11s %11s\n", "", "#", "decrypted", "power", "exact",
+		"abs err", "rel err", "angle pow", "angle ex")
+	for i := 0; i < k; i++ {
+		absErr := math.Abs(eigenVals[i] - exactVals[i])
+		fmt.Printf("%2s%-3d %14.8f %14.8f %14.8f %11.3e %11.3e %10.4f° %10.4f°\n", "", i+1, eigenVals[i], refVals[i],
+			exactVals[i], absErr, absErr/math.Abs(exactVals[i]), angle(eigenVecs[i], refVecs[i]),
+			angle(eigenVecs[i], exactVecs[i]))
+	}
+
+	orth := 0.0
+	for i := 0; i < k; i++ {
+		for j := 0; j < k; j++ {
+			g := dot(eigenVecs[i], eigenVecs[j])
+			if i == j {
+				g -= 1
+			}
+			orth += g * g
+		}
+	}
+	fmt.Printf("%2sorthogonality ||V^T V - I||_F: %.3e\n", "", math.Sqrt(orth))
+
+	frob := math.Sqrt(frobenius2(A))
+	fmt.Printf("%2sreconstruction ||A - V L V^T||_F / ||A||_F: %.3e (exact rank %d: %.3e)\n", "",
+		math.Sqrt(frobenius2(reconstructionResidual(A, eigenVecs, eigenVals)))/frob, k,
+		math.Sqrt(frobenius2(reconstructionResidual(A, exactVecs[:k], exactVals[:k])))/frob)
+}
+
+// angle returns the angle in degrees between the lines spanned by u and v.
+func angle(u []float64, v []float64) float64 {
+	cos := math.Abs(dot(u, v)) / math.Sqrt(dot(u, u)*dot(v, v))
+	return math.Acos(math.Min(cos, 1)) * 180 / math.Pi
+}
+
+func reconstructionResidual(A [][]float64, eigenVecs [][]float64, eigenVals []float64) (R [][]float64) {
+	R = A
+	for i := range eigenVals {
+		R = subOuter(R, eigenVals[i], eigenVecs[i])
+	}
+	return R
+}
+
+// subOuter returns A - lambda*v*v^T.
+func subOuter(A [][]float64, lambda float64, v []float64) (B [][]float64) {
+	B = make([][]float64, len(A))
+	for i := range A {
+		B[i] = make([]float64, len(A[i]))
+		for j := range A[i] {
+			B[i][j] = A[i][j] - lambda*v[i]*v[j]
+		}
+	}
+	return B
+}
+
+// subMatrix returns A - B.
+func subMatrix(A [][]float64, B [][]float64) (C [][]float64) {
+	C = make([][]float64, len(A))
+	for i := range A {
+		C[i] = make([]float64, len(A[i]))
+		for j := range A[i] {
+			C[i][j] = A[i][j] - B[i][j]
+		}
+	}
+	return C
+}
+
+func matVec(A [][]float64, v []float64) (w []float64) {
+	w = make([]float64, len(A))
+	for i := range A {
+		w[i] = dot(A[i], v)
+	}
+	return w
+}
+
+func dot(u []float64, v []float64) (s float64) {
+	for i := range u {
+		s += u[i] * v[i]
+	}
+	return s
+}
+
+func scale(v []float64, c float64) (w []float64) {
+	w = make([]float64, len(v))
+	for i := range v {
+		w[i] = c * v[i]
+	}
+	return w
+}
+
+func frobenius2(A [][]float64) (s float64) {
+	for _, row := range A {
+		s += dot(row, row)
+	}
+	return s
+}
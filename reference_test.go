@@ -0,0 +1,39 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestJacobiEigen(t *testing.T) {
+	cases := append(spectrumCases[:len(spectrumCases):len(spectrumCases)], spectrumCase{"indefinite",
+		householderConjugate(diagonalMatrix(-3, 2, 1, 0.5), []float64{0.5, 0.5, 0.5, 0.5}), []float64{-3, 2, 1, 0.5}})
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			eigenVals, eigenVecs := JacobiEigen(tc.A)
+			for i, val := range tc.vals {
+				if math.Abs(eigenVals[i]-val) > 1e-9 {
+					t.Errorf("eigenvalues %v, want %v", eigenVals, tc.vals)
+					break
+				}
+			}
+			for i, vec := range eigenVecs {
+				r := matVec(tc.A, vec)
+				for j := range r {
+					if math.Abs(r[j]-eigenVals[i]*vec[j]) > 1e-9 {
+						t.Errorf("eigenpair %d: A v - lambda v = %v in entry %d", i+1, r[j]-eigenVals[i]*vec[j], j)
+					}
+				}
+				for j := 0; j <= i; j++ {
+					want := 0.0
+					if i == j {
+						want = 1
+					}
+					if have := dot(vec, eigenVecs[j]); math.Abs(have-want) > 1e-9 {
+						t.Errorf("<v_%d, v_%d> = %v, want %v", i+1, j+1, have, want)
+					}
+				}
+			}
+		})
+	}
+}
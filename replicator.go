@@ -0,0 +1,44 @@
+package main
+
+import (
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+	"github.com/tuneinsight/lattigo/v6/schemes/ckks"
+	"src/eigen/tracer"
+)
+
+// Replicator copies a vector in slots [0, n) count times, caching the last input.
+type Replicator struct {
+	n     int
+	count int
+	ctIn  *rlwe.Ciphertext
+	ctOut *rlwe.Ciphertext
+}
+
+func NewReplicator(n int, count int) *Replicator {
+	return &Replicator{n: n, count: count}
+}
+
+// MatVecReplicas returns the number of copies of the vector of a product by an n x n matrix.
+func MatVecReplicas(Slots int, n int) int {
+	return Slots/n - 1
+}
+
+func ReplicatorGaloisElements(params ckks.Parameters, n int, count int) (galEls []uint64) {
+	return params.GaloisElementsForReplicate(n, count)
+}
+
+// Replicate returns the replicated ctVec, shared with the cache.
+func (rep *Replicator) Replicate(ctVec *rlwe.Ciphertext, eval tracer.Evaluator) (ctRepVec *rlwe.Ciphertext) {
+	if rep.ctIn != nil && rep.ctIn.Equal(ctVec) {
+		return rep.ctOut
+	}
+
+	ctRepVec = ctVec.CopyNew()
+	if err := eval.Replicate(ctRepVec, rep.n, rep.count, ctRepVec); err != nil {
+		panic(err)
+	}
+
+	rep.ctIn = ctVec.CopyNew()
+	rep.ctOut = ctRepVec
+	return ctRepVec
+}
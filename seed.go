@@ -0,0 +1,118 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"github.com/tuneinsight/lattigo/v6/circuits/ckks/bootstrapping"
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+	"github.com/tuneinsight/lattigo/v6/ring"
+	"github.com/tuneinsight/lattigo/v6/ring/ringqp"
+	"github.com/tuneinsight/lattigo/v6/utils/sampling"
+	"reflect"
+	"unsafe"
+)
+
+// seedKey hashes seed, label and index into the key of a PRNG stream.
+func seedKey(seed int64, label string, index int) (key []byte) {
+	h := sha256.New()
+	if err := binary.Write(h, binary.LittleEndian, [2]int64{seed, int64(index)}); err != nil {
+		panic(err)
+	}
+	h.Write([]byte(label))
+	return h.Sum(nil)
+}
+
+// SeededPRNG returns the keyed PRNG of the stream label of seed.
+func SeededPRNG(seed int64, label string) (prng *sampling.KeyedPRNG) {
+	prng, err := sampling.NewKeyedPRNG(seedKey(seed, label, 0))
+	if err != nil {
+		panic(err)
+	}
+	return prng
+}
+
+// SeedEncryptor sets the samplers of enc, unexported in lattigo, to draw from prng.
+func SeedEncryptor(enc *rlwe.Encryptor, prng sampling.PRNG) {
+	params := enc.GetRLWEParameters()
+	xeSampler, err := ring.NewSampler(prng, params.RingQ(), params.Xe(), false)
+	if err != nil {
+		panic(err)
+	}
+	xsSampler, err := ring.NewSampler(prng, params.RingQ(), params.Xs(), false)
+	if err != nil {
+		panic(err)
+	}
+
+	v := reflect.ValueOf(enc).Elem()
+	for name, val := range map[string]any{
+		"prng":           prng,
+		"xeSampler":      xeSampler,
+		"xsSampler":      xsSampler,
+		"uniformSampler": ringqp.NewUniformSampler(prng, *params.RingQP()),
+	} {
+		field := v.FieldByName(name)
+		if !field.IsValid() {
+			panic(fmt.Errorf("rlwe.Encryptor has no field %s", name))
+		}
+		reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem().Set(reflect.ValueOf(val))
+	}
+}
+
+// NewSeededKeyGenerator returns a key generator drawing from the stream label of seed.
+func NewSeededKeyGenerator(params rlwe.ParameterProvider, seed int64, label string) (kgen *rlwe.KeyGenerator) {
+	kgen = rlwe.NewKeyGenerator(params)
+	SeedEncryptor(kgen.Encryptor, SeededPRNG(seed, label))
+	return kgen
+}
+
+// NewSeededEncryptor returns an encryptor under key drawing from the stream label of seed.
+func NewSeededEncryptor(params rlwe.ParameterProvider, key rlwe.EncryptionKey, seed int64,
+	label string) (enc *rlwe.Encryptor) {
+
+	enc = rlwe.NewEncryptor(params, key)
+	SeedEncryptor(enc, SeededPRNG(seed, label))
+	return enc
+}
+
+// SeededBootstrappingKeys is btpParams.GenEvaluationKeys with seeded key generators.
+func SeededBootstrappingKeys(btpParams bootstrapping.Parameters, sk *rlwe.SecretKey,
+	seed int64) (btpEvk *bootstrapping.EvaluationKeys) {
+
+	paramsN2 := btpParams.BootstrappingParameters
+	if btpParams.ResidualParameters.N() != paramsN2.N() {
+		panic(fmt.Errorf("seeded bootstrapping keys need the ring degree %d of the residual parameters, have %d",
+			btpParams.ResidualParameters.N(), paramsN2.N()))
+	}
+
+	// sk extended to the moduli of the bootstrapping parameters
+	ringQ, ringP := paramsN2.RingQ(), paramsN2.RingP()
+	skN2 := rlwe.NewSecretKey(paramsN2)
+	buff := ringQ.NewPoly()
+	rlwe.ExtendBasisSmallNormAndCenterNTTMontgomery(ringQ, ringQ, sk.Value.Q, buff, skN2.Value.Q)
+	rlwe.ExtendBasisSmallNormAndCenterNTTMontgomery(ringQ, ringP, sk.Value.Q, buff, skN2.Value.P)
+
+	btpEvk = &bootstrapping.EvaluationKeys{}
+	if btpParams.EphemeralSecretWeight > 0 {
+		paramsSparse, err := rlwe.NewParametersFromLiteral(rlwe.ParametersLiteral{
+			LogN: paramsN2.LogN(),
+			Q:    paramsN2.Q()[:1],
+			P:    paramsN2.P()[:1],
+		})
+		if err != nil {
+			panic(err)
+		}
+		skSparse := NewSeededKeyGenerator(paramsSparse, seed, "sparse secret key").
+			GenSecretKeyWithHammingWeightNew(btpParams.EphemeralSecretWeight)
+
+		kgenDense := NewSeededKeyGenerator(paramsN2, seed, "encapsulation keys")
+		btpEvk.EvkDenseToSparse = kgenDense.GenEvaluationKeyNew(skN2, skSparse)
+		btpEvk.EvkSparseToDense = kgenDense.GenEvaluationKeyNew(skSparse, skN2)
+	}
+
+	kgen := NewSeededKeyGenerator(paramsN2, seed, "bootstrapping keys")
+	galEls := append(btpParams.GaloisElements(paramsN2), paramsN2.GaloisElementForComplexConjugation())
+	btpEvk.MemEvaluationKeySet = rlwe.NewMemEvaluationKeySet(kgen.GenRelinearizationKeyNew(skN2),
+		kgen.GenGaloisKeysNew(galEls, skN2)...)
+	return btpEvk
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"github.com/tuneinsight/lattigo/v6/circuits/ckks/bootstrapping"
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+	"github.com/tuneinsight/lattigo/v6/schemes/ckks"
+	"math"
+	"slices"
+	"src/eigen/fixture"
+	"testing"
+)
+
+// seededRun generates every key of seed as main does, runs the power method
+// on A from the seeded start vector and returns the marshalled eigenvector and
+// eigenvalue ciphertexts with the decrypted eigenvalue.
+func seededRun(ctx *benchContext, seed int64, A [][]float64, bootstrap bool) (ctBytes [][]byte, eigenVal float64) {
+	n := len(A)
+	Slots := ctx.Params.MaxSlots()
+	kgen := NewSeededKeyGenerator(ctx.Params, seed, "keys")
+	sk := kgen.GenSecretKeyNew()
+	pk := kgen.GenPublicKeyNew(sk)
+	rlk := kgen.GenRelinearizationKeyNew(sk)
+	eval := NewPipelineEvaluator(ctx.Params, PipelineGaloisElements(ctx.Params, PipelineOptions{Slots: Slots, N: n,
+		Batch: 1}), kgen, rlk, sk)
+	enc := NewSeededEncryptor(ctx.Params, pk, seed, "encryption")
+	dec := rlwe.NewDecryptor(ctx.Params, sk)
+
+	pt := ckks.NewPlaintext(ctx.Params, ctx.Params.MaxLevel())
+	if err := ctx.Ecd.Encode(StartVectors(seed, 0, 1, n)[0], pt); err != nil {
+		panic(err)
+	}
+	ctVec, err := enc.EncryptNew(pt)
+	if err != nil {
+		panic(err)
+	}
+	cts := []*rlwe.Ciphertext{ctVec}
+
+	if bootstrap {
+		btpEval, err := bootstrapping.NewEvaluator(ctx.BtpParams, SeededBootstrappingKeys(ctx.BtpParams, sk, seed))
+		if err != nil {
+			panic(err)
+		}
+		lt, ltEval := LinearTrans(A, Slots, n, ctVec, ctx.Params, ctx.Ecd, eval)
+		_, ctEigenVec, ctEigenVal := HomomoPowerMethod(lt, ltEval, ctVec, eval, dec, ctx.Ecd, Slots, testIters, 1, n,
+			ctx.Encode(fixture.F1), ctx.Encode(fixture.F2), ctx.Encode(fixture.A), ctx.Encode(fixture.B), btpEval,
+			fixture.D, NewReplicator(n, MatVecReplicas(Slots, n)), nil, nil, 1, nil)
+		cts = append(cts, ctEigenVec, ctEigenVal)
+
+		values := make([]float64, Slots)
+		if err = ctx.Ecd.Decode(dec.DecryptNew(ctEigenVal), values); err != nil {
+			panic(err)
+		}
+		eigenVal = values[0]
+	}
+
+	for _, ct := range cts {
+		data, err := ct.MarshalBinary()
+		if err != nil {
+			panic(err)
+		}
+		ctBytes = append(ctBytes, data)
+	}
+	return ctBytes, eigenVal
+}
+
+func TestStartVectorsSeed(t *testing.T) {
+	// With seed + i the pairs (s, i) and (s+1, i-1) drew the same vectors
+	if slices.Equal(StartVectors(3, 1, 1, 4)[0], StartVectors(4, 0, 1, 4)[0]) {
+		t.Errorf("seeds 3 and 4 share the start vectors of consecutive eigenpairs")
+	}
+	if !slices.Equal(StartVectors(3, 1, 1, 4)[0], StartVectors(3, 1, 1, 4)[0]) {
+		t.Errorf("the start vectors of seed 3 differ between calls")
+	}
+}
+
+func TestSeededKeys(t *testing.T) {
+	ctx := getBenchContext()
+	A := spectrumCases[0].A
+
+	first, _ := seededRun(ctx, 3, A, false)
+	second, _ := seededRun(ctx, 3, A, false)
+	other, _ := seededRun(ctx, 4, A, false)
+	if !bytes.Equal(first[0], second[0]) {
+		t.Errorf("two runs of seed 3 encrypt the start vector differently")
+	}
+	if bytes.Equal(first[0], other[0]) {
+		t.Errorf("seeds 3 and 4 give the same ciphertext")
+	}
+}
+
+func TestSeededPipeline(t *testing.T) {
+	if testing.Short() {
+		t.Skip("generates the bootstrapping keys twice and bootstraps about 60 times")
+	}
+	ctx := getBenchContext()
+	A := spectrumCases[0].A
+
+	first, eigenVal := seededRun(ctx, 3, A, true)
+	second, _ := seededRun(ctx, 3, A, true)
+	for i := range first {
+		if !bytes.Equal(first[i], second[i]) {
+			t.Errorf("ciphertext %d differs between two runs of seed 3", i)
+		}
+	}
+
+	// The seeded bootstrapping keys are valid ones
+	_, refVal := ReferencePowerMethod(A, StartVectors(3, 0, 1, len(A))[0], testIters, false, fixture.D, fixture.A,
+		fixture.B, fixture.F1, fixture.F2)
+	if err := math.Abs(eigenVal-refVal) / refVal; err > 1e-3 {
+		t.Errorf("eigenvalue %v, plaintext power method %v", eigenVal, refVal)
+	}
+}
@@ -1,78 +1,71 @@
 package main
 
 import (
+	"github.com/tuneinsight/lattigo/v6/circuits/ckks/bootstrapping"
 	"github.com/tuneinsight/lattigo/v6/core/rlwe"
 	"github.com/tuneinsight/lattigo/v6/schemes/ckks"
 	"math"
+	"src/eigen/tracer"
 )
 
 
-func HomomoOuterProduct(ptVector *rlwe.Plaintext, ctVec *rlwe.Ciphertext,
-	eval *ckks.Evaluator, n int, evalInnsum *ckks.Evaluator, batch int,
-	params ckks.Parameters, kgen *rlwe.KeyGenerator, rlk *rlwe.RelinearizationKey, sk *rlwe.SecretKey,
-	ctVec0 *rlwe.Ciphertext, ctVec00 *rlwe.Ciphertext, ecd *ckks.Encoder) (ctVecOuter *rlwe.Ciphertext) {
-	var err error
+// HomomoOuterProduct returns v*v^T in the row-major layout for v in slots [0, n).
+func HomomoOuterProduct(ctVec *rlwe.Ciphertext, eval tracer.Evaluator, n int, batch int,
+	params ckks.Parameters, ecd *ckks.Encoder, workers int) (ctVecOuter *rlwe.Ciphertext) {
 
-	mask_vecs := make([][]float64, n)
-	for i := 0; i < n; i++ {
-		mask_vecs[i] = make([]float64, n) 
-	}
+	return HomomoOuterProductBlocks(ctVec, eval, n, batch, 1, n*n, params, ecd, workers)
+}
 
-	
-	for i := 0; i < n; i++ {
-		mask_vecs[i][i] = 1.0 
+// HomomoOuterProductBlocks returns the outer product of the vector of every block in its block.
+func HomomoOuterProductBlocks(ctVec *rlwe.Ciphertext, eval tracer.Evaluator, n int, batch int, blocks int,
+	stride int, params ckks.Parameters, ecd *ckks.Encoder, workers int) (ctVecOuter *rlwe.Ciphertext) {
+	var err error
+	if err = CheckGaloisKeys(eval, OuterProductGaloisElements(params, n, batch)); err != nil {
+		panic(err)
 	}
-	for i, vector := range mask_vecs {
-		if err = ecd.Encode(vector, ptVector); err != nil {
-			panic(err)
-		}
-		tempVec, err := eval.MulRelinNew(ctVec, ptVector)
-		if err != nil {
-			panic(err)
-		}
-
-		
-		rotLeft := -(n - 1) * (i + 1)
-		galElsRotLeft := []uint64{
-			params.GaloisElement(rotLeft)}
-		rotEvalLeft := eval.WithKey(rlwe.NewMemEvaluationKeySet(rlk, kgen.GenGaloisKeysNew(galElsRotLeft, sk)...))
 
-		tempVec, err = rotEvalLeft.RotateNew(tempVec, rotLeft)
-		if err != nil {
-			panic(err)
-		}
-
-		
-		//[1,0,0,0]->[0,0,0,1]->[1,1,1,1]
-		if err := evalInnsum.InnerSum(tempVec, batch, n, tempVec); err != nil {
-			panic(err)
-		}
-
-		ctVec0, err = eval.AddNew(ctVec0, tempVec)
-		if err != nil {
-			panic(err)
+	nPow := int(math.Pow(float64(n), 2))
+	diagMask := make([]float64, (blocks-1)*stride+nPow)
+	upperMask := make([]float64, len(diagMask))
+	lowerMask := make([]float64, len(diagMask))
+	for b := 0; b < blocks; b++ {
+		for i := 0; i < n; i++ {
+			diagMask[b*stride+i*n+i] = 1.0
+			for j := 0; j < n; j++ {
+				if j >= i {
+					upperMask[b*stride+i*n+j] = 1.0
+				} else {
+					lowerMask[b*stride+i*n+j] = 1.0
+				}
+			}
 		}
 	}
-	ctVecLeft := ctVec0
 
-	
-
-	rotRight := -n
-	galElsRotRight := []uint64{
-		params.GaloisElement(rotRight)}
-	rotEvalRight := eval.WithKey(rlwe.NewMemEvaluationKeySet(rlk, kgen.GenGaloisKeysNew(galElsRotRight, sk)...))
+	//[1,2,3,0,...]->[1,2,3,1,2,3,1,2,3]
+	ctVecRight := ctVec.CopyNew()
+	if err = eval.Replicate(ctVecRight, n*batch, n, ctVecRight); err != nil {
+		panic(err)
+	}
 
-	for i := 0; i < n; i++ {
-		ctVec00, err = eval.AddNew(ctVec00, ctVec)
-		if err != nil {
-			panic(err)
+	//[1,2,3,1,2,3,1,2,3]->[1,0,0,0,2,0,0,0,3]
+	ctDiag := mulPlainRescale(ctVecRight, diagMask, params, eval, ecd)
+
+	//[1,0,0,0,2,0,0,0,3]->[1,1,1,0,2,2,0,0,3] & [1,0,0,2,2,0,3,3,3]
+	//->[1,1,1,2,2,2,3,3,3]
+	ctHalves := ParallelEval(workers, 2, eval, ecd, func(i int, eval tracer.Evaluator, ecd *ckks.Encoder) *rlwe.Ciphertext {
+		ctHalf := ctDiag.CopyNew()
+		if i == 0 {
+			if err := eval.Replicate(ctHalf, batch, n, ctHalf); err != nil {
+				panic(err)
+			}
+			return mulPlainRescale(ctHalf, upperMask, params, eval, ecd)
 		}
-		ctVec, err = rotEvalRight.RotateNew(ctVec, rotRight)
-		if err != nil {
+		if err := eval.InnerSum(ctHalf, batch, n, ctHalf); err != nil {
 			panic(err)
 		}
-	}
-	ctVecRight := ctVec00
+		return mulPlainRescale(ctHalf, lowerMask, params, eval, ecd)
+	})
+	ctVecLeft := SumInOrder(ctHalves, eval)
 
 	ctVecOuter, err = eval.MulRelinNew(ctVecLeft, ctVecRight)
 	if err != nil {
@@ -85,43 +78,93 @@ func HomomoOuterProduct(ptVector *rlwe.Plaintext, ctVec *rlwe.Ciphertext,
 	return ctVecOuter
 }
 
+func mulPlainRescale(ct *rlwe.Ciphertext, mask []float64, params ckks.Parameters,
+	eval tracer.Evaluator, ecd *ckks.Encoder) (ctOut *rlwe.Ciphertext) {
+
+	ptVector := NewSlotsPlaintext(params, ct.Level(), ct.Slots())
+	if err := ecd.Encode(mask, ptVector); err != nil {
+		panic(err)
+	}
+	ctOut, err := eval.MulRelinNew(ct, ptVector)
+	if err != nil {
+		panic(err)
+	}
+	if err = eval.Rescale(ctOut, ctOut); err != nil {
+		panic(err)
+	}
+	return ctOut
+}
+
+// HomomoEigenShift returns A - lambda*v*v^T and the rank-one term lambda*v*v^T.
 func HomomoEigenShift(ctRowVec *rlwe.Ciphertext, ctEigenVec *rlwe.Ciphertext, ctEigenVal *rlwe.Ciphertext,
-	rotEval *ckks.Evaluator, rot int, ptVector *rlwe.Plaintext, eval *ckks.Evaluator, n int,
-	evalInnsum *ckks.Evaluator, batch int, params ckks.Parameters, kgen *rlwe.KeyGenerator,
-	rlk *rlwe.RelinearizationKey, sk *rlwe.SecretKey, ctVec0 *rlwe.Ciphertext,
-	ctVec00 *rlwe.Ciphertext, ctVec000 *rlwe.Ciphertext, ecd *ckks.Encoder) (ctShiftMat *rlwe.Ciphertext) {
+	eval tracer.Evaluator, n int, batch int, params ckks.Parameters,
+	ecd *ckks.Encoder, btpEval *bootstrapping.Evaluator, workers int, trace *PrecisionTrace) (ctShiftMat *rlwe.Ciphertext,
+	ctRankOne *rlwe.Ciphertext) {
 
-	var err error
-	ctVecOuter := HomomoOuterProduct(ptVector, ctEigenVec, eval, n, evalInnsum, batch, params, kgen, rlk, sk, ctVec0, ctVec00, ecd)
+	return HomomoEigenShiftBlocks(ctRowVec, ctEigenVec, ctEigenVal, eval, n, batch, 1, n*n, params, ecd, btpEval, workers,
+		trace)
+}
 
-	
-	// multi & add & rotate
-	nPow := math.Pow(float64(n), 2)
-	for i := 0; i < int(nPow); i++ {
-		tempVec, err := eval.MulRelinNew(ctEigenVal, ctVecOuter)
-		if err != nil {
-			panic(err)
-		}
-		if err = eval.Rescale(tempVec, tempVec); err != nil {
-			panic(err)
-		}
+// HomomoEigenShiftBlocks returns A_b - lambda_b*v_b*v_b^T and lambda_b*v_b*v_b^T in every block b.
+func HomomoEigenShiftBlocks(ctRowVec *rlwe.Ciphertext, ctEigenVec *rlwe.Ciphertext, ctEigenVal *rlwe.Ciphertext,
+	eval tracer.Evaluator, n int, batch int, blocks int, stride int, params ckks.Parameters,
+	ecd *ckks.Encoder, btpEval *bootstrapping.Evaluator, workers int, trace *PrecisionTrace) (ctShiftMat *rlwe.Ciphertext,
+	ctRankOne *rlwe.Ciphertext) {
 
-		ctVec000, err = eval.AddNew(ctVec000, tempVec)
-		if err != nil {
-			panic(err)
+	ctVecOuter, ctRankOne := HomomoRankOneBlocks(ctEigenVec, ctEigenVal, eval, n, batch, blocks, stride, params, ecd,
+		btpEval, workers)
+
+	ctShiftMat, err := eval.SubNew(ctRowVec, ctRankOne)
+	if err != nil {
+		panic(err)
+	}
+	if trace != nil {
+		trace.RecordEigenShift(ctRowVec, ctEigenVec, ctEigenVal, ctVecOuter, ctShiftMat)
+	}
+
+	return ctShiftMat, ctRankOne
+}
+
+// HomomoRankOne returns the outer product v*v^T and the rank-one term lambda*v*v^T.
+func HomomoRankOne(ctEigenVec *rlwe.Ciphertext, ctEigenVal *rlwe.Ciphertext, eval tracer.Evaluator, n int, batch int,
+	params ckks.Parameters, ecd *ckks.Encoder, btpEval *bootstrapping.Evaluator, workers int) (ctVecOuter *rlwe.Ciphertext,
+	ctRankOne *rlwe.Ciphertext) {
+
+	return HomomoRankOneBlocks(ctEigenVec, ctEigenVal, eval, n, batch, 1, n*n, params, ecd, btpEval, workers)
+}
+
+// HomomoRankOneBlocks returns v_b*v_b^T and lambda_b*v_b*v_b^T in every block b.
+func HomomoRankOneBlocks(ctEigenVec *rlwe.Ciphertext, ctEigenVal *rlwe.Ciphertext, eval tracer.Evaluator, n int,
+	batch int, blocks int, stride int, params ckks.Parameters, ecd *ckks.Encoder, btpEval *bootstrapping.Evaluator,
+	workers int) (ctVecOuter *rlwe.Ciphertext, ctRankOne *rlwe.Ciphertext) {
+
+	var err error
+	ctEigenVec = EnsureLevel(ctEigenVec, StageEigenShift, btpEval)
+	ctEigenVal = EnsureLevel(ctEigenVal, StageEigenShiftVal, btpEval)
+	nPow := math.Pow(float64(n), 2)
+	cts := ParallelEval(workers, 2, eval, ecd, func(i int, eval tracer.Evaluator, ecd *ckks.Encoder) *rlwe.Ciphertext {
+		if i == 0 {
+			// Inner workers would only oversubscribe the pool
+			return HomomoOuterProductBlocks(ctEigenVec, eval, n, batch, blocks, stride, params, ecd, 1)
 		}
 
-		ctEigenVal, err = rotEval.RotateNew(ctEigenVal, rot)
-		if err != nil {
+		// replicate
+		ctEigenVals := ctEigenVal.CopyNew()
+		if err := eval.Replicate(ctEigenVals, 1, int(nPow), ctEigenVals); err != nil {
 			panic(err)
 		}
-	}
-	ctEigenValMulOuterVec := ctVec000
+		return ctEigenVals
+	})
+	ctVecOuter, ctEigenVals := cts[0], cts[1]
 
-	ctShiftMat, err = eval.SubNew(ctRowVec, ctEigenValMulOuterVec)
+	// multi
+	ctRankOne, err = eval.MulRelinNew(ctEigenVals, ctVecOuter)
 	if err != nil {
 		panic(err)
 	}
+	if err = eval.Rescale(ctRankOne, ctRankOne); err != nil {
+		panic(err)
+	}
 
-	return ctShiftMat
+	return ctVecOuter, ctRankOne
 }
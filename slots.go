@@ -0,0 +1,53 @@
+package main
+
+import (
+	"github.com/tuneinsight/lattigo/v6/circuits/ckks/bootstrapping"
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+	"github.com/tuneinsight/lattigo/v6/ring"
+	"github.com/tuneinsight/lattigo/v6/schemes/ckks"
+	"math/bits"
+)
+
+// SparseLogSlots returns the log2 of the fewest slots holding the matrix, the start blocks and the matrices.
+func SparseLogSlots(params ckks.Parameters, n int, starts int, stride int, matrices int) (LogSlots int) {
+	size := n*n + n
+	if starts > 1 && 2*starts*stride > size {
+		size = 2 * starts * stride
+	}
+	if matrices > 1 && matrices*MatrixStride(n) > size {
+		size = matrices * MatrixStride(n)
+	}
+
+	LogSlots = bits.Len(uint(size - 1))
+	if LogSlots > params.LogMaxSlots() {
+		LogSlots = params.LogMaxSlots()
+	}
+	return LogSlots
+}
+
+// NewSlotsPlaintext returns a plaintext at level encoding Slots values.
+func NewSlotsPlaintext(params ckks.Parameters, level int, Slots int) (pt *rlwe.Plaintext) {
+	pt = ckks.NewPlaintext(params, level)
+	pt.LogDimensions = ring.Dimensions{Rows: 0, Cols: bits.Len(uint(Slots)) - 1}
+	return pt
+}
+
+// SparseBootstrapper bootstraps with Evaluate, which keeps sparse slots where Bootstrap repacks them.
+type SparseBootstrapper struct {
+	*bootstrapping.Evaluator
+}
+
+func (btp SparseBootstrapper) Bootstrap(ct *rlwe.Ciphertext) (*rlwe.Ciphertext, error) {
+	return btp.Evaluate(ct)
+}
+
+func (btp SparseBootstrapper) BootstrapMany(cts []rlwe.Ciphertext) ([]rlwe.Ciphertext, error) {
+	for i := range cts {
+		ct, err := btp.Evaluate(&cts[i])
+		if err != nil {
+			return nil, err
+		}
+		cts[i] = *ct
+	}
+	return cts, nil
+}
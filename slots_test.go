@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"github.com/tuneinsight/lattigo/v6/circuits/ckks/bootstrapping"
+	"github.com/tuneinsight/lattigo/v6/schemes/ckks"
+	"github.com/tuneinsight/lattigo/v6/utils"
+	"math/rand"
+	"testing"
+)
+
+func TestSparseLogSlots(t *testing.T) {
+	ctx := getBenchContext()
+	for _, tc := range []struct {
+		name             string
+		n, starts, mats  int
+		size, LogSlotsIn int
+	}{
+		{"n=1", 1, 1, 1, 2, 1},
+		{"n=4", 4, 1, 1, 20, 5},
+		{"n=4, 4 starts", 4, 4, 1, 2 * 4 * MultiStartStride(4), 6},
+		{"n=8, 2 starts", 8, 2, 1, 72, 7},
+		{"n=3, 5 matrices", 3, 1, 5, 5 * MatrixStride(3), 7},
+		{"n=30, beyond the slots", 30, 1, 1, 930, ctx.Params.LogMaxSlots()},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			LogSlots := SparseLogSlots(ctx.Params, tc.n, tc.starts, MultiStartStride(tc.n), tc.mats)
+			if LogSlots != tc.LogSlotsIn {
+				t.Errorf("LogSlots = %d, want %d", LogSlots, tc.LogSlotsIn)
+			}
+			if LogSlots < ctx.Params.LogMaxSlots() && (1<<LogSlots < tc.size || 1<<(LogSlots-1) >= tc.size) {
+				t.Errorf("%d slots are not the fewest holding %d", 1<<LogSlots, tc.size)
+			}
+		})
+	}
+}
+
+// bootstrapPrecision returns the average log2 precision of btpEval on random
+// values in Slots slots encrypted at level 2.
+func bootstrapPrecision(ctx *benchContext, btpEval *bootstrapping.Evaluator, Slots int) float64 {
+	r := rand.New(rand.NewSource(int64(Slots)))
+	values := make([]float64, Slots)
+	for i := range values {
+		values[i] = 2*r.Float64() - 1
+	}
+	pt := NewSlotsPlaintext(ctx.Params, 2, Slots)
+	if err := ctx.Ecd.Encode(values, pt); err != nil {
+		panic(err)
+	}
+	ct, err := ctx.Enc.EncryptNew(pt)
+	if err != nil {
+		panic(err)
+	}
+	if ct, err = btpEval.Evaluate(ct); err != nil {
+		panic(err)
+	}
+	return ckks.GetPrecisionStats(ctx.Params, ctx.Ecd, ctx.Dec, values, ct, 0, false).AVGLog2Prec.Real
+}
+
+// TestSparseBootstrapping bootstraps at the LogSlots of the -starts and
+// -matrices padding with the message ratio of the full slots, and checks it
+// is at least as precise as the full-slot bootstrapping.
+func TestSparseBootstrapping(t *testing.T) {
+	if testing.Short() {
+		t.Skip("generates bootstrapping keys per case")
+	}
+	ctx := getBenchContext()
+	fullPrec := bootstrapPrecision(ctx, ctx.BtpEval, ctx.Params.MaxSlots())
+
+	for _, LogSlots := range []int{
+		SparseLogSlots(ctx.Params, 4, 4, MultiStartStride(4), 1),
+		SparseLogSlots(ctx.Params, 3, 1, MultiStartStride(3), 5),
+	} {
+		t.Run(fmt.Sprintf("LogSlots=%d", LogSlots), func(t *testing.T) {
+			btpParams, err := bootstrapping.NewParametersFromLiteral(ctx.Params, bootstrapping.ParametersLiteral{
+				LogN:     utils.Pointy(ctx.Params.LogN()),
+				LogSlots: utils.Pointy(LogSlots),
+				LogP:     []int{61, 61, 61, 61},
+				Xs:       ctx.Params.Xs(),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			btpParams.Mod1ParametersLiteral.LogMessageRatio = ctx.BtpParams.Mod1ParametersLiteral.LogMessageRatio
+			btpEvk, _, err := btpParams.GenEvaluationKeys(ctx.Sk)
+			if err != nil {
+				t.Fatal(err)
+			}
+			btpEval, err := bootstrapping.NewEvaluator(btpParams, btpEvk)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if prec := bootstrapPrecision(ctx, btpEval, 1<<LogSlots); prec < fullPrec {
+				t.Errorf("%.2f bits of precision, %.2f on the full slots", prec, fullPrec)
+			}
+		})
+	}
+}
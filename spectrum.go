@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+	"github.com/tuneinsight/lattigo/v6/schemes/ckks"
+	"math"
+	"src/eigen/tracer"
+)
+
+// Spectrum modes, running the power method on B = A, c*I - A or c*I - (A - s*I)^2.
+const (
+	SpectrumLargest  = "largest"
+	SpectrumSmallest = "smallest"
+	SpectrumNearest  = "nearest"
+)
+
+// GershgorinBound returns max_i sum_j |A[i][j]|, a bound on the eigenvalues of A.
+func GershgorinBound(A [][]float64) (bound float64) {
+	for _, row := range A {
+		sum := 0.0
+		for _, val := range row {
+			sum += math.Abs(val)
+		}
+		bound = math.Max(bound, sum)
+	}
+	return bound
+}
+
+// EncodeScaledIdentity returns c*I in the row-major layout of ctRowA over Slots slots.
+func EncodeScaledIdentity(c float64, n int, Slots int, params ckks.Parameters, ecd *ckks.Encoder) (ptIdentity *rlwe.Plaintext) {
+	identity := make([]float64, n*n)
+	for i := 0; i < n; i++ {
+		identity[i*n+i] = c
+	}
+
+	ptIdentity = NewSlotsPlaintext(params, params.MaxLevel(), Slots)
+	if err := ecd.Encode(identity, ptIdentity); err != nil {
+		panic(err)
+	}
+	return ptIdentity
+}
+
+// HomomoIdentityMinusMat returns c*I - M in the row-major layout.
+func HomomoIdentityMinusMat(ctRowMat *rlwe.Ciphertext, c float64, n int, params ckks.Parameters,
+	ecd *ckks.Encoder, eval tracer.Evaluator) (ctShifted *rlwe.Ciphertext) {
+
+	ctShifted, err := eval.MulNew(ctRowMat, -1)
+	if err != nil {
+		panic(err)
+	}
+	if err = eval.Add(ctShifted, EncodeScaledIdentity(c, n, ctRowMat.Slots(), params, ecd), ctShifted); err != nil {
+		panic(err)
+	}
+	return ctShifted
+}
+
+// HomomoMatSquare returns M*M for a symmetric row-major M as the sum of the outer products of its rows.
+func HomomoMatSquare(ctRowMat *rlwe.Ciphertext, eval tracer.Evaluator, n int,
+	batch int, params ckks.Parameters, ctVec0 *rlwe.Ciphertext, ecd *ckks.Encoder, workers int) (ctSquare *rlwe.Ciphertext) {
+
+	fmt.Println()
+	fmt.Printf("%4sPerforming homomorphic matrix square...", "")
+
+	var err error
+	if err = CheckGaloisKeys(eval, MatSquareGaloisElements(params, n)); err != nil {
+		panic(err)
+	}
+	ctOuters := ParallelEval(workers, n, eval, ecd, func(k int, eval tracer.Evaluator, ecd *ckks.Encoder) *rlwe.Ciphertext {
+		rowMask := make([]float64, n*n)
+		for j := 0; j < n; j++ {
+			rowMask[k*n+j] = 1.0
+		}
+		ctRow := mulPlainRescale(ctRowMat, rowMask, params, eval, ecd)
+
+		// Bring row k to slots [0, n)
+		if k > 0 {
+			var err error
+			if ctRow, err = eval.RotateNew(ctRow, k*n); err != nil {
+				panic(err)
+			}
+		}
+
+		return HomomoOuterProduct(ctRow, eval, n, batch, params, ecd, 1)
+	})
+
+	if ctSquare, err = eval.AddNew(ctVec0, SumInOrder(ctOuters, eval)); err != nil {
+		panic(err)
+	}
+
+	fmt.Println()
+
+	return ctSquare
+}
+
+// HomomoSpectralShift returns the matrix B of the spectrum mode for the row-major A.
+func HomomoSpectralShift(mode string, ctRowA *rlwe.Ciphertext, bound float64, target float64,
+	eval tracer.Evaluator, n int, batch int, params ckks.Parameters, ctVec0 *rlwe.Ciphertext,
+	ecd *ckks.Encoder, workers int) (ctRowB *rlwe.Ciphertext) {
+
+	switch mode {
+	case SpectrumLargest:
+		return ctRowA
+	case SpectrumSmallest:
+		return HomomoIdentityMinusMat(ctRowA, bound, n, params, ecd, eval)
+	case SpectrumNearest:
+		ctShifted, err := eval.SubNew(ctRowA, EncodeScaledIdentity(target, n, ctRowA.Slots(), params, ecd))
+		if err != nil {
+			panic(err)
+		}
+		ctSquare := HomomoMatSquare(ctShifted, eval, n, batch, params, ctVec0, ecd, workers)
+		return HomomoIdentityMinusMat(ctSquare, bound, n, params, ecd, eval)
+	default:
+		panic(fmt.Errorf("unknown spectrum mode %q", mode))
+	}
+}
+
+// HomomoSmallestEigenVal returns the eigenvalue c - mu of A for the eigenvalue mu of c*I - A.
+func HomomoSmallestEigenVal(ctEigenVal *rlwe.Ciphertext, ptBound *rlwe.Plaintext,
+	eval tracer.Evaluator) (ctOrigEigenVal *rlwe.Ciphertext) {
+
+	ctOrigEigenVal, err := eval.MulNew(ctEigenVal, -1)
+	if err != nil {
+		panic(err)
+	}
+	if err = eval.Add(ctOrigEigenVal, ptBound, ctOrigEigenVal); err != nil {
+		panic(err)
+	}
+	return ctOrigEigenVal
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"math"
+	"src/eigen/fixture"
+	"testing"
+)
+
+// spectralShift returns the decrypted matrix B of the spectrum mode for A.
+func spectralShift(ctx *benchContext, mode string, A [][]float64, bound float64, target float64) (B [][]float64) {
+	n := len(A)
+	Slots := ctx.Params.MaxSlots()
+	eval := ctx.evalWith(PipelineOptions{N: n, Square: mode == SpectrumNearest})
+	ctRowB := HomomoSpectralShift(mode, ctx.encryptMatrix(A), bound, target, eval, n, 1,
+		ctx.Params, ctx.Encrypt(make([]float64, n*n), ctx.Params.MaxLevel()), ctx.Ecd, 1)
+	return DecryptMatrix(ctRowB, n, ctx.Dec, ctx.Ecd, Slots)
+}
+
+// checkMatrix compares the decrypted matrix have with want.
+func checkMatrix(t *testing.T, have [][]float64, want [][]float64) {
+	t.Helper()
+	for i := range want {
+		for j := range want[i] {
+			if math.Abs(have[i][j]-want[i][j]) > 1e-3 {
+				t.Fatalf("entry (%d, %d): have %v, want %v", i, j, have[i][j], want[i][j])
+			}
+		}
+	}
+}
+
+func TestHomomoSpectralShiftSmallest(t *testing.T) {
+	if testing.Short() {
+		t.Skip("bootstraps about 30 times")
+	}
+	ctx := getBenchContext()
+
+	// 6, 5.5, 5, 1 with c = 6.5: B = c*I - A has the eigenvalues 0.5, 1, 1.5, 5.5
+	A := householderConjugate(diagonalMatrix(6, 5.5, 5, 1), []float64{0.5, 0.5, 0.5, 0.5})
+	n, bound := len(A), 6.5
+	B := spectralShift(ctx, SpectrumSmallest, A, bound, 0)
+
+	checkMatrix(t, B, subMatrix(diagonalMatrix(bound, bound, bound, bound), A))
+
+	vec := []float64{0.6, -0.3, -0.8, 0.4}
+	eigenVec, eigenVal := powerMethod(ctx, B, vec)
+	refVec, refVal := ReferencePowerMethod(B, vec, testIters, false, fixture.D, fixture.A, fixture.B, fixture.F1, fixture.F2)
+	checkEigenpair(t, B, eigenVec, eigenVal, refVec, refVal, bound-1)
+
+	// The eigenvector of B is the one of the smallest eigenvalue 1 of A
+	ctOrigEigenVal := HomomoSmallestEigenVal(ctx.Encrypt([]float64{eigenVal}, ctx.Params.MaxLevel()), ctx.Encode(bound),
+		ctx.eval(n))
+	if have := ctx.Decrypt(ctOrigEigenVal, 1)[0]; math.Abs(have-(bound-eigenVal)) > 1e-4 {
+		t.Errorf("eigenvalue %v of A, want c - mu = %v", have, bound-eigenVal)
+	}
+	if r := eigenResidual(A, eigenVec, 1); r > 0.05 {
+		t.Errorf("eigenvector residual %.2e for the smallest eigenvalue 1", r)
+	}
+}
+
+func TestHomomoSpectralShiftNearest(t *testing.T) {
+	if testing.Short() {
+		t.Skip("bootstraps about 30 times")
+	}
+	ctx := getBenchContext()
+
+	// 3.5, -2.5, 3.5, 0.2 with s = 0.5 and c = 9: B = c*I - (A - s*I)^2 has the
+	// eigenvalues 0, 0, 0, 8.91, that of the eigenvalue 0.2 nearest to s
+	A := householderConjugate(diagonalMatrix(3.5, -2.5, 3.5, 0.2), []float64{0.5, 0.5, 0.5, 0.5})
+	n, bound, target := len(A), 9.0, 0.5
+	B := spectralShift(ctx, SpectrumNearest, A, bound, target)
+
+	shifted := subMatrix(A, diagonalMatrix(target, target, target, target))
+	square := make([][]float64, n)
+	for i := range square {
+		square[i] = matVec(shifted, shifted[i])
+	}
+	checkMatrix(t, B, subMatrix(diagonalMatrix(bound, bound, bound, bound), square))
+
+	vec := []float64{0.6, -0.3, -0.8, 0.4}
+	eigenVec, eigenVal := powerMethod(ctx, B, vec)
+	refVec, refVal := ReferencePowerMethod(B, vec, testIters, false, fixture.D, fixture.A, fixture.B, fixture.F1, fixture.F2)
+	checkEigenpair(t, B, eigenVec, eigenVal, refVec, refVal, bound-(0.2-target)*(0.2-target))
+
+	// The eigenvalue of A is the Rayleigh quotient of the eigenvector of B
+	eval := ctx.eval(n)
+	Slots := ctx.Params.MaxSlots()
+	ctEigenVec := ctx.Encrypt(eigenVec, ctx.Params.MaxLevel())
+	ltOrig, ltEvalOrig := LinearTrans(A, Slots, n, ctEigenVec, ctx.Params, ctx.Ecd, eval)
+	ctLintransOrig := HomomoMatMutiVec(ltOrig, ltEvalOrig, ctEigenVec, eval, NewReplicator(n, MatVecReplicas(Slots, n)))
+	ctOrigEigenVal := HomomoEigenVal(ctLintransOrig, ctEigenVec, eval, 1, n, ctx.Encode(fixture.F1), ctx.Encode(fixture.F2),
+		ctx.Encode(fixture.A), ctx.Encode(fixture.B), ctx.BtpEval, fixture.D)
+
+	origVal := ctx.Decrypt(ctOrigEigenVal, 1)[0]
+	if refOrigVal := ReferenceEigenVal(A, eigenVec, fixture.D, fixture.A, fixture.B, fixture.F1, fixture.F2); math.Abs(origVal-refOrigVal) > 1e-3 {
+		t.Errorf("eigenvalue %v of A, plaintext %v", origVal, refOrigVal)
+	}
+	if err := math.Abs(origVal-0.2) / 0.2; err > 0.08 {
+		t.Errorf("eigenvalue %v of A, exact 0.2: relative error %.2e", origVal, err)
+	}
+	if r := eigenResidual(A, eigenVec, 0.2); r > 0.05 {
+		t.Errorf("eigenvector residual %.2e for the nearest eigenvalue 0.2", r)
+	}
+}
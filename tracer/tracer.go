@@ -0,0 +1,341 @@
+package tracer
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+	"github.com/tuneinsight/lattigo/v6/schemes"
+	"github.com/tuneinsight/lattigo/v6/schemes/ckks"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Evaluator is the part of *ckks.Evaluator the pipeline uses.
+type Evaluator interface {
+	schemes.Evaluator
+	InnerSum(ctIn *rlwe.Ciphertext, batchSize int, n int, opOut *rlwe.Ciphertext) (err error)
+	Replicate(ctIn *rlwe.Ciphertext, batchSize int, n int, opOut *rlwe.Ciphertext) (err error)
+	Rotate(op0 *rlwe.Ciphertext, k int, opOut *rlwe.Ciphertext) (err error)
+	RotateNew(op0 *rlwe.Ciphertext, k int) (opOut *rlwe.Ciphertext, err error)
+}
+
+// ShallowCopy returns a shallow copy of eval, safe to use on another goroutine.
+func ShallowCopy(eval Evaluator) Evaluator {
+	switch eval := eval.(type) {
+	case *ckks.Evaluator:
+		return eval.ShallowCopy()
+	case *Tracer:
+		return eval.ShallowCopy()
+	}
+	panic(fmt.Errorf("cannot copy an evaluator of type %T", eval))
+}
+
+// Unwrap returns the *ckks.Evaluator of eval, whose operations are not traced.
+func Unwrap(eval Evaluator) *ckks.Evaluator {
+	switch eval := eval.(type) {
+	case *ckks.Evaluator:
+		return eval
+	case *Tracer:
+		return eval.Evaluator
+	}
+	panic(fmt.Errorf("cannot unwrap an evaluator of type %T", eval))
+}
+
+// Operand is the level and scale of an operand.
+type Operand struct {
+	Level     int     `json:"level"`
+	Log2Scale float64 `json:"log2_scale"`
+	Plaintext bool    `json:"plaintext,omitempty"`
+}
+
+// Record is one operation of the evaluator, its call site, noise and flags.
+type Record struct {
+	Op        string    `json:"op"`
+	Site      string    `json:"site"`
+	In        []Operand `json:"in"`
+	Out       Operand   `json:"out"`
+	Log2Noise *float64  `json:"log2_noise"`
+	Flags     []string  `json:"flags,omitempty"`
+}
+
+// sink holds the records of a Tracer and of its shallow copies.
+type sink struct {
+	mu      sync.Mutex
+	ecd     *ckks.Encoder
+	dec     *rlwe.Decryptor
+	Records []Record
+}
+
+// Tracer is a *ckks.Evaluator recording a Record for every operation of Evaluator.
+type Tracer struct {
+	*ckks.Evaluator
+	sink *sink
+}
+
+// NewTracer returns a Tracer of eval. dec may be nil, then no noise is estimated.
+func NewTracer(eval *ckks.Evaluator, ecd *ckks.Encoder, dec *rlwe.Decryptor) *Tracer {
+	return &Tracer{Evaluator: eval, sink: &sink{ecd: ecd.ShallowCopy(), dec: dec}}
+}
+
+// ShallowCopy returns a Tracer of a shallow copy of the evaluator sharing the records.
+func (eval *Tracer) ShallowCopy() *Tracer {
+	return &Tracer{Evaluator: eval.Evaluator.ShallowCopy(), sink: eval.sink}
+}
+
+// Records returns the records so far.
+func (eval *Tracer) Records() []Record {
+	eval.sink.mu.Lock()
+	defer eval.sink.mu.Unlock()
+	return append([]Record(nil), eval.sink.Records...)
+}
+
+func operands(ops ...rlwe.Operand) (in []Operand) {
+	for _, op := range ops {
+		switch op := op.(type) {
+		case *rlwe.Ciphertext:
+			in = append(in, Operand{Level: op.Level(), Log2Scale: op.Scale.Log2()})
+		case *rlwe.Plaintext:
+			in = append(in, Operand{Level: op.Level(), Log2Scale: op.Scale.Log2(), Plaintext: true})
+		}
+	}
+	return in
+}
+
+// site returns the first caller outside this package and lattigo.
+func site() string {
+	pc := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pc[:runtime.Callers(3, pc)])
+	for {
+		frame, more := frames.Next()
+		if !strings.Contains(frame.Function, "/tracer.") && !strings.Contains(frame.Function, "tuneinsight/lattigo") {
+			return fmt.Sprintf("%s:%d %s", filepath.Base(frame.File), frame.Line, frame.Function)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
+// scaleTolerance is the difference of log2 scales flagged as a mismatch.
+const scaleTolerance = 1e-3
+
+// record appends the record of op from in to out with its flags.
+func (eval *Tracer) record(op string, in []Operand, out *rlwe.Ciphertext, scales bool, drop int, flags ...string) {
+	rec := Record{Op: op, Site: site(), In: in, Out: Operand{Level: out.Level(), Log2Scale: out.Scale.Log2()}}
+
+	minLevel := math.MaxInt
+	for i, operand := range in {
+		minLevel = min(minLevel, operand.Level)
+		// Plaintexts are encoded at the top level and dropped to the ciphertext
+		if i > 0 && !operand.Plaintext && !in[0].Plaintext && operand.Level != in[0].Level {
+			rec.Flags = append(rec.Flags, fmt.Sprintf("level mismatch: %d and %d, the higher one is dropped",
+				in[0].Level, operand.Level))
+		}
+		// Rescales divide by the moduli and not by 2^LogDefaultScale, which
+		// drifts the scales by much less than scaleTolerance
+		if scales && i > 0 && math.Abs(operand.Log2Scale-in[0].Log2Scale) > scaleTolerance {
+			rec.Flags = append(rec.Flags, fmt.Sprintf("scale mismatch: 2^%.4f and 2^%.4f", in[0].Log2Scale,
+				operand.Log2Scale))
+		}
+	}
+	if len(in) > 0 && out.Level() < minLevel-drop {
+		rec.Flags = append(rec.Flags, fmt.Sprintf("level drop: %d levels from %d", minLevel-out.Level(), minLevel))
+	}
+	rec.Flags = append(rec.Flags, flags...)
+
+	eval.sink.mu.Lock()
+	defer eval.sink.mu.Unlock()
+	if eval.sink.dec != nil {
+		rec.Log2Noise = eval.sink.noise(out)
+	}
+	eval.sink.Records = append(eval.sink.Records, rec)
+}
+
+// noise returns the log2 of the root mean square of the imaginary part of ct.
+func (s *sink) noise(ct *rlwe.Ciphertext) *float64 {
+	values := make([]complex128, ct.Slots())
+	if err := s.ecd.Decode(s.dec.DecryptNew(ct), values); err != nil {
+		panic(err)
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += imag(v) * imag(v)
+	}
+	log2Noise := math.Log2(math.Sqrt(sum / float64(len(values))))
+	if math.IsInf(log2Noise, 0) || math.IsNaN(log2Noise) {
+		return nil
+	}
+	return &log2Noise
+}
+
+func (eval *Tracer) Add(op0 *rlwe.Ciphertext, op1 rlwe.Operand, opOut *rlwe.Ciphertext) (err error) {
+	in := operands(op0, op1)
+	if err = eval.Evaluator.Add(op0, op1, opOut); err == nil {
+		eval.record("Add", in, opOut, true, 0)
+	}
+	return err
+}
+
+func (eval *Tracer) AddNew(op0 *rlwe.Ciphertext, op1 rlwe.Operand) (opOut *rlwe.Ciphertext, err error) {
+	in := operands(op0, op1)
+	if opOut, err = eval.Evaluator.AddNew(op0, op1); err == nil {
+		eval.record("AddNew", in, opOut, true, 0)
+	}
+	return opOut, err
+}
+
+func (eval *Tracer) Sub(op0 *rlwe.Ciphertext, op1 rlwe.Operand, opOut *rlwe.Ciphertext) (err error) {
+	in := operands(op0, op1)
+	if err = eval.Evaluator.Sub(op0, op1, opOut); err == nil {
+		eval.record("Sub", in, opOut, true, 0)
+	}
+	return err
+}
+
+func (eval *Tracer) SubNew(op0 *rlwe.Ciphertext, op1 rlwe.Operand) (opOut *rlwe.Ciphertext, err error) {
+	in := operands(op0, op1)
+	if opOut, err = eval.Evaluator.SubNew(op0, op1); err == nil {
+		eval.record("SubNew", in, opOut, true, 0)
+	}
+	return opOut, err
+}
+
+func (eval *Tracer) Mul(op0 *rlwe.Ciphertext, op1 rlwe.Operand, opOut *rlwe.Ciphertext) (err error) {
+	in := operands(op0, op1)
+	if err = eval.Evaluator.Mul(op0, op1, opOut); err == nil {
+		eval.record("Mul", in, opOut, false, 0)
+	}
+	return err
+}
+
+func (eval *Tracer) MulNew(op0 *rlwe.Ciphertext, op1 rlwe.Operand) (opOut *rlwe.Ciphertext, err error) {
+	in := operands(op0, op1)
+	if opOut, err = eval.Evaluator.MulNew(op0, op1); err == nil {
+		eval.record("MulNew", in, opOut, false, 0)
+	}
+	return opOut, err
+}
+
+func (eval *Tracer) MulRelin(op0 *rlwe.Ciphertext, op1 rlwe.Operand, opOut *rlwe.Ciphertext) (err error) {
+	in := operands(op0, op1)
+	if err = eval.Evaluator.MulRelin(op0, op1, opOut); err == nil {
+		eval.record("MulRelin", in, opOut, false, 0)
+	}
+	return err
+}
+
+func (eval *Tracer) MulRelinNew(op0 *rlwe.Ciphertext, op1 rlwe.Operand) (opOut *rlwe.Ciphertext, err error) {
+	in := operands(op0, op1)
+	if opOut, err = eval.Evaluator.MulRelinNew(op0, op1); err == nil {
+		eval.record("MulRelinNew", in, opOut, false, 0)
+	}
+	return opOut, err
+}
+
+// MulThenAdd adds op0*op1 to opOut, whose scale must match the product.
+func (eval *Tracer) MulThenAdd(op0 *rlwe.Ciphertext, op1 rlwe.Operand, opOut *rlwe.Ciphertext) (err error) {
+	in := operands(op0, op1, opOut)
+	if err = eval.Evaluator.MulThenAdd(op0, op1, opOut); err == nil {
+		eval.record("MulThenAdd", in, opOut, false, 0)
+	}
+	return err
+}
+
+func (eval *Tracer) Relinearize(op0 *rlwe.Ciphertext, opOut *rlwe.Ciphertext) (err error) {
+	in := operands(op0)
+	if err = eval.Evaluator.Relinearize(op0, opOut); err == nil {
+		eval.record("Relinearize", in, opOut, false, 0)
+	}
+	return err
+}
+
+// Rescale is flagged when it brings the scale below half the default scale.
+func (eval *Tracer) Rescale(op0 *rlwe.Ciphertext, opOut *rlwe.Ciphertext) (err error) {
+	in := operands(op0)
+	if err = eval.Evaluator.Rescale(op0, opOut); err == nil {
+		var flags []string
+		if defaultScale := eval.GetParameters().DefaultScale(); opOut.Scale.Log2() < defaultScale.Log2()-1 {
+			flags = append(flags, fmt.Sprintf("rescaled below the default scale: 2^%.4f", opOut.Scale.Log2()))
+		}
+		eval.record("Rescale", in, opOut, false, 1, flags...)
+	}
+	return err
+}
+
+func (eval *Tracer) InnerSum(ctIn *rlwe.Ciphertext, batchSize int, n int, opOut *rlwe.Ciphertext) (err error) {
+	in := operands(ctIn)
+	if err = eval.Evaluator.InnerSum(ctIn, batchSize, n, opOut); err == nil {
+		eval.record("InnerSum", in, opOut, false, 0)
+	}
+	return err
+}
+
+func (eval *Tracer) Replicate(ctIn *rlwe.Ciphertext, batchSize int, n int, opOut *rlwe.Ciphertext) (err error) {
+	in := operands(ctIn)
+	if err = eval.Evaluator.Replicate(ctIn, batchSize, n, opOut); err == nil {
+		eval.record("Replicate", in, opOut, false, 0)
+	}
+	return err
+}
+
+func (eval *Tracer) Rotate(op0 *rlwe.Ciphertext, k int, opOut *rlwe.Ciphertext) (err error) {
+	in := operands(op0)
+	if err = eval.Evaluator.Rotate(op0, k, opOut); err == nil {
+		eval.record("Rotate", in, opOut, false, 0)
+	}
+	return err
+}
+
+func (eval *Tracer) RotateNew(op0 *rlwe.Ciphertext, k int) (opOut *rlwe.Ciphertext, err error) {
+	in := operands(op0)
+	if opOut, err = eval.Evaluator.RotateNew(op0, k); err == nil {
+		eval.record("RotateNew", in, opOut, false, 0)
+	}
+	return opOut, err
+}
+
+// PrintSummary prints the number of operations and the flagged call sites.
+func (eval *Tracer) PrintSummary() {
+	records := eval.Records()
+	type siteFlags struct {
+		count int
+		flags []string
+	}
+	var sites []string
+	flagged := map[string]*siteFlags{}
+	for _, rec := range records {
+		if len(rec.Flags) == 0 {
+			continue
+		}
+		key := rec.Op + " at " + rec.Site
+		if flagged[key] == nil {
+			sites = append(sites, key)
+			flagged[key] = &siteFlags{flags: rec.Flags}
+		}
+		flagged[key].count++
+	}
+
+	fmt.Printf("%2s%d operations traced, %d call sites flagged\n", "", len(records), len(sites))
+	for _, key := range sites {
+		fmt.Printf("%4s%s (%d times): %s\n", "", key, flagged[key].count, strings.Join(flagged[key].flags, "; "))
+	}
+}
+
+// WriteJSON writes the records to path.
+func (eval *Tracer) WriteJSON(path string) {
+	file, err := os.Create(path)
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err = encoder.Encode(eval.Records()); err != nil {
+		panic(err)
+	}
+}
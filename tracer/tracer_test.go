@@ -0,0 +1,160 @@
+package tracer
+
+import (
+	"fmt"
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+	"github.com/tuneinsight/lattigo/v6/schemes/ckks"
+	"src/eigen/fixture"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func newTestTracer(ctx *fixture.Context) *Tracer {
+	return NewTracer(ctx.NewEvaluator(nil), ctx.Ecd, ctx.Dec)
+}
+
+// checkFlags fails unless the flags of rec start with prefixes, in order.
+func checkFlags(t *testing.T, rec Record, prefixes ...string) {
+	t.Helper()
+	if len(rec.Flags) != len(prefixes) {
+		t.Fatalf("%s flags = %q, want %q", rec.Op, rec.Flags, prefixes)
+	}
+	for i, prefix := range prefixes {
+		if !strings.HasPrefix(rec.Flags[i], prefix) {
+			t.Fatalf("%s flags = %q, want %q", rec.Op, rec.Flags, prefixes)
+		}
+	}
+}
+
+func TestTracerFlags(t *testing.T) {
+	ctx := fixture.Get()
+	values := []float64{0.5, -0.25, 0.125}
+	maxLevel := ctx.Params.MaxLevel()
+
+	cases := []struct {
+		name  string
+		op    func(eval *Tracer) error
+		flags []string
+	}{
+		{"matching", func(eval *Tracer) error {
+			_, err := eval.AddNew(ctx.Encrypt(values, maxLevel), ctx.Encrypt(values, maxLevel))
+			return err
+		}, nil},
+		{"level mismatch", func(eval *Tracer) error {
+			_, err := eval.AddNew(ctx.Encrypt(values, maxLevel), ctx.Encrypt(values, maxLevel-3))
+			return err
+		}, []string{"level mismatch"}},
+		{"plaintext", func(eval *Tracer) error {
+			_, err := eval.MulNew(ctx.Encrypt(values, maxLevel-3), ctx.Encode(0.5))
+			return err
+		}, nil},
+		{"scale mismatch", func(eval *Tracer) error {
+			ct := ctx.Encrypt(values, maxLevel)
+			ctSquare, err := eval.MulRelinNew(ct, ct)
+			if err != nil {
+				return err
+			}
+			_, err = eval.AddNew(ctSquare, ct)
+			return err
+		}, []string{"scale mismatch"}},
+		{"level drop", func(eval *Tracer) error {
+			return eval.Add(ctx.Encrypt(values, maxLevel), ctx.Encrypt(values, maxLevel),
+				ckks.NewCiphertext(ctx.Params, 1, maxLevel-2))
+		}, []string{"level drop: 2 levels"}},
+		{"rescale", func(eval *Tracer) error {
+			ct := ctx.Encrypt(values, maxLevel)
+			ctSquare, err := eval.MulRelinNew(ct, ct)
+			if err != nil {
+				return err
+			}
+			return eval.Rescale(ctSquare, ctSquare)
+		}, nil},
+		{"rescale below the default scale", func(eval *Tracer) error {
+			ct := ctx.Encrypt(values, maxLevel)
+			return eval.Rescale(ct, ct)
+		}, []string{"rescaled below the default scale"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			eval := newTestTracer(ctx)
+			if err := c.op(eval); err != nil {
+				t.Fatal(err)
+			}
+			records := eval.Records()
+			checkFlags(t, records[len(records)-1], c.flags...)
+		})
+	}
+}
+
+func TestTracerNoise(t *testing.T) {
+	ctx := fixture.Get()
+	eval := newTestTracer(ctx)
+	ct := ctx.Encrypt([]float64{0.5, -0.25}, ctx.Params.MaxLevel())
+	if _, err := eval.AddNew(ct, ct); err != nil {
+		t.Fatal(err)
+	}
+	// A fresh encryption carries noise of a few units over 2^LogDefaultScale
+	rec := eval.Records()[0]
+	if rec.Log2Noise == nil || *rec.Log2Noise > -20 {
+		t.Fatalf("log2 noise = %v, want below -20", rec.Log2Noise)
+	}
+}
+
+// TestTracerShallowCopies records from concurrent shallow copies, each
+// alternating flagged and unflagged operations, and checks that every flag
+// is on the record of its own operation.
+func TestTracerShallowCopies(t *testing.T) {
+	ctx := fixture.Get()
+	const goroutines, iters = 8, 16
+
+	cts := make([][]*rlwe.Ciphertext, goroutines)
+	for g := range cts {
+		cts[g] = make([]*rlwe.Ciphertext, iters)
+		for i := range cts[g] {
+			cts[g][i] = ctx.Encrypt([]float64{float64(g), float64(i)}, ctx.Params.MaxLevel())
+		}
+	}
+
+	eval := NewTracer(ctx.NewEvaluator(nil), ctx.Ecd, nil)
+	var wg sync.WaitGroup
+	errs := make([]error, goroutines)
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int, eval *Tracer) {
+			defer wg.Done()
+			for _, ct := range cts[g] {
+				if errs[g] = eval.Rescale(ct, ct); errs[g] != nil {
+					return
+				}
+				if errs[g] = eval.Add(ct, ct, ct); errs[g] != nil {
+					return
+				}
+			}
+		}(g, eval.ShallowCopy())
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records := eval.Records()
+	if len(records) != 2*goroutines*iters {
+		t.Fatalf("%d records, want %d", len(records), 2*goroutines*iters)
+	}
+	for i, rec := range records {
+		t.Run(fmt.Sprintf("record %d", i), func(t *testing.T) {
+			switch rec.Op {
+			case "Rescale":
+				checkFlags(t, rec, "rescaled below the default scale")
+			case "Add":
+				checkFlags(t, rec)
+			default:
+				t.Fatalf("unexpected %s", rec.Op)
+			}
+		})
+	}
+}
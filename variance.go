@@ -0,0 +1,100 @@
+package main
+
+import (
+	"github.com/tuneinsight/lattigo/v6/circuits/ckks/bootstrapping"
+	"github.com/tuneinsight/lattigo/v6/circuits/ckks/comparison"
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+	"github.com/tuneinsight/lattigo/v6/schemes/ckks"
+	"src/eigen/normalize"
+	"src/eigen/tracer"
+)
+
+// Reveal policies of the explained-variance stopping, the value decrypted after every eigenpair.
+const (
+	VarianceRevealBit   = "bit"
+	VarianceRevealRatio = "ratio"
+)
+
+func VarianceGaloisElements(params ckks.Parameters, n int) (galEls []uint64) {
+	galEls = append(galEls, params.GaloisElementsForInnerSum(1, n*n)...)
+	return append(galEls, params.GaloisElementForComplexConjugation())
+}
+
+// HomomoTrace returns tr(A) in slot 0 and zero elsewhere for the row-major A.
+func HomomoTrace(ctRowA *rlwe.Ciphertext, eval tracer.Evaluator, n int, params ckks.Parameters,
+	ecd *ckks.Encoder) (ctTrace *rlwe.Ciphertext) {
+
+	if err := CheckGaloisKeys(eval, VarianceGaloisElements(params, n)); err != nil {
+		panic(err)
+	}
+
+	diagMask := make([]float64, n*n)
+	for i := 0; i < n; i++ {
+		diagMask[i*n+i] = 1.0
+	}
+	ctTrace = mulPlainRescale(ctRowA, diagMask, params, eval, ecd)
+	if err := eval.InnerSum(ctTrace, 1, n*n, ctTrace); err != nil {
+		panic(err)
+	}
+	return mulPlainRescale(ctTrace, []float64{1}, params, eval, ecd)
+}
+
+// HomomoVarianceReached returns in slot 0 the step of ctEigenSum - fraction*ctTrace.
+func HomomoVarianceReached(ctEigenSum *rlwe.Ciphertext, ctTrace *rlwe.Ciphertext, fraction float64, cmpScale float64,
+	cmpEval *comparison.Evaluator, eval tracer.Evaluator) (ctReached *rlwe.Ciphertext) {
+
+	ctThreshold, err := eval.MulNew(ctTrace, fraction)
+	if err != nil {
+		panic(err)
+	}
+	if err = eval.Rescale(ctThreshold, ctThreshold); err != nil {
+		panic(err)
+	}
+
+	ctDiff, err := eval.SubNew(ctEigenSum, ctThreshold)
+	if err != nil {
+		panic(err)
+	}
+	if err = eval.Mul(ctDiff, cmpScale, ctDiff); err != nil {
+		panic(err)
+	}
+	if err = eval.Rescale(ctDiff, ctDiff); err != nil {
+		panic(err)
+	}
+
+	if ctReached, err = cmpEval.Step(ctDiff); err != nil {
+		panic(err)
+	}
+	return ctReached
+}
+
+// HomomoInverse returns 1/x as the square of the inverse square root of HomomoNewton.
+func HomomoInverse(ctx *rlwe.Ciphertext, eval tracer.Evaluator, ptf1 *rlwe.Plaintext, ptf2 *rlwe.Plaintext,
+	pta *rlwe.Plaintext, ptb *rlwe.Plaintext, btpEval *bootstrapping.Evaluator, d int) (ctInv *rlwe.Ciphertext) {
+
+	cty0 := normalize.LinearApprox(ctx, eval, pta, ptb)
+	ctyd := normalize.HomomoNewton(ptf1, ptf2, ctx, cty0, eval, btpEval, d)
+
+	ctInv, err := eval.MulRelinNew(ctyd, ctyd)
+	if err != nil {
+		panic(err)
+	}
+	if err = eval.Rescale(ctInv, ctInv); err != nil {
+		panic(err)
+	}
+	return ctInv
+}
+
+// HomomoVarianceRatio returns the explained variance ctEigenSum * ctInvTrace.
+func HomomoVarianceRatio(ctEigenSum *rlwe.Ciphertext, ctInvTrace *rlwe.Ciphertext,
+	eval tracer.Evaluator) (ctRatio *rlwe.Ciphertext) {
+
+	ctRatio, err := eval.MulRelinNew(ctEigenSum, ctInvTrace)
+	if err != nil {
+		panic(err)
+	}
+	if err = eval.Rescale(ctRatio, ctRatio); err != nil {
+		panic(err)
+	}
+	return ctRatio
+}
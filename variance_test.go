@@ -0,0 +1,67 @@
+package main
+
+import (
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+	"github.com/tuneinsight/lattigo/v6/schemes/ckks"
+	"math"
+	"src/eigen/fixture"
+	"testing"
+)
+
+// varianceCase is the circulant matrix of trace 20 and eigenvalues 12, 4, 4, 0:
+// the running sums 12, 16 and 20 explain 60%, 80% and 100% of the variance.
+func varianceCase(ctx *benchContext) (A [][]float64, eval *ckks.Evaluator, ctRowA *rlwe.Ciphertext) {
+	A = spectrumCases[1].A
+	return A, ctx.evalWith(PipelineOptions{N: len(A), Variance: true}), ctx.encryptMatrix(A)
+}
+
+func TestHomomoTrace(t *testing.T) {
+	ctx := getBenchContext()
+	A, eval, ctRowA := varianceCase(ctx)
+	n := len(A)
+
+	have := ctx.Decrypt(HomomoTrace(ctRowA, eval, n, ctx.Params, ctx.Ecd), n*n)
+	if math.Abs(have[0]-20) > 1e-4 {
+		t.Errorf("trace %v, want 20", have[0])
+	}
+	for i := 1; i < n*n; i++ {
+		if math.Abs(have[i]) > 1e-4 {
+			t.Fatalf("slot %d holds %v, want 0", i, have[i])
+		}
+	}
+}
+
+func TestHomomoVarianceReached(t *testing.T) {
+	if testing.Short() {
+		t.Skip("bootstraps for every comparison")
+	}
+	ctx := getBenchContext()
+	A, eval, ctRowA := varianceCase(ctx)
+	n := len(A)
+	eigenVals, _ := JacobiEigen(A)
+
+	ctTrace := HomomoTrace(ctRowA, eval, n, ctx.Params, ctx.Ecd)
+	ctInvTrace := HomomoInverse(ctTrace, eval, ctx.Encode(fixture.F1), ctx.Encode(fixture.F2), ctx.Encode(fixture.A),
+		ctx.Encode(fixture.B), ctx.BtpEval, fixture.D)
+	cmpEval := NewComparisonEvaluator(ctx.Params, eval, ctx.BtpEval)
+	cmpScale := 1 / (2 * math.Sqrt(float64(n)*frobenius2(A)))
+
+	// 70% is reached by the second eigenvalue only
+	fraction := 0.7
+	var ctEigenSum *rlwe.Ciphertext
+	sum := 0.0
+	for i, val := range eigenVals[:3] {
+		ctEigenSum = HomomoAccumulate(ctEigenSum, ctx.Encrypt([]float64{val}, ctx.Params.MaxLevel()), eval)
+		sum += val
+
+		ratio := ctx.Decrypt(HomomoVarianceRatio(ctEigenSum, ctInvTrace, eval), 1)[0]
+		if math.Abs(ratio-sum/20) > 1e-3 {
+			t.Errorf("eigenpair %d: explained variance %v, want %v", i+1, ratio, sum/20)
+		}
+
+		reached := ctx.Decrypt(HomomoVarianceReached(ctEigenSum, ctTrace, fraction, cmpScale, cmpEval, eval), 1)[0]
+		if want := sum >= fraction*20; (reached > 0.5) != want || math.Abs(reached-0.5) < 0.4 {
+			t.Errorf("eigenpair %d: step %v for the explained variance %v of the fraction %v", i+1, reached, sum/20, fraction)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+	"github.com/tuneinsight/lattigo/v6/schemes/ckks"
+	"src/eigen/tracer"
+	"sync"
+)
+
+// ParallelEval returns the outputs of task for i in [0, count), run on up to workers shallow copies of eval and ecd.
+func ParallelEval(workers int, count int, eval tracer.Evaluator, ecd *ckks.Encoder,
+	task func(i int, eval tracer.Evaluator, ecd *ckks.Encoder) *rlwe.Ciphertext) (cts []*rlwe.Ciphertext) {
+
+	cts = make([]*rlwe.Ciphertext, count)
+	if workers <= 1 || count <= 1 {
+		for i := 0; i < count; i++ {
+			cts[i] = task(i, eval, ecd)
+		}
+		return cts
+	}
+
+	if workers > count {
+		workers = count
+	}
+
+	indices := make(chan int, count)
+	for i := 0; i < count; i++ {
+		indices <- i
+	}
+	close(indices)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(eval tracer.Evaluator, ecd *ckks.Encoder) {
+			defer wg.Done()
+			for i := range indices {
+				cts[i] = task(i, eval, ecd)
+			}
+		}(tracer.ShallowCopy(eval), ecd.ShallowCopy())
+	}
+	wg.Wait()
+
+	return cts
+}
+
+// SumInOrder returns cts[0] + cts[1] + ... added in index order.
+func SumInOrder(cts []*rlwe.Ciphertext, eval tracer.Evaluator) (ctSum *rlwe.Ciphertext) {
+	var err error
+	ctSum = cts[0]
+	for _, ct := range cts[1:] {
+		if ctSum, err = eval.AddNew(ctSum, ct); err != nil {
+			panic(err)
+		}
+	}
+	return ctSum
+}